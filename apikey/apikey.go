@@ -0,0 +1,241 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/writdev-alt/portal-api-shared/rbac"
+)
+
+const (
+	// KeyPrefix starts every generated key so leaked keys are easy to recognise
+	KeyPrefix = "pk"
+
+	publicIDBytes = 6
+	secretBytes   = 32
+
+	// lastUsedInterval throttles last-used writes for busy keys
+	lastUsedInterval = time.Minute
+)
+
+var (
+	ErrInvalidKey      = errors.New("invalid API key")
+	ErrKeyExpired      = errors.New("API key has expired")
+	ErrKeyRevoked      = errors.New("API key has been revoked")
+	ErrKeyNotFound     = errors.New("API key not found")
+	ErrScopeNotGranted = errors.New("API key scope not granted")
+)
+
+// APIKey is a merchant API key. Only the SHA-256 hash of the key is stored; the
+// plaintext is returned once when the key is created.
+type APIKey struct {
+	ID         uint           `gorm:"primaryKey" json:"-"`
+	UUID       string         `gorm:"type:char(36);uniqueIndex" json:"uuid"`
+	MerchantID string         `gorm:"type:varchar(64);index" json:"merchantId"`
+	Name       string         `gorm:"type:varchar(100)" json:"name"`
+	Prefix     string         `gorm:"type:varchar(32);uniqueIndex" json:"prefix"` // Public part of the key, safe to display
+	Hash       string         `gorm:"type:char(64)" json:"-"`
+	Scopes     []string       `gorm:"serializer:json" json:"scopes"`
+	ExpiresAt  *time.Time     `json:"expiresAt"`
+	RevokedAt  *time.Time     `json:"revokedAt"`
+	LastUsedAt *time.Time     `json:"lastUsedAt"`
+	CreatedAt  time.Time      `json:"createdAt"`
+	UpdatedAt  time.Time      `json:"updatedAt"`
+	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// TableName returns the table name for APIKey
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// HasScope reports whether the key grants a scope. Scopes support the same
+// wildcards as rbac permissions, e.g. "payments.*".
+func (k *APIKey) HasScope(scope string) bool {
+	for _, granted := range k.Scopes {
+		if rbac.Match(granted, scope) {
+			return true
+		}
+	}
+	return false
+}
+
+// Active reports whether the key is neither revoked nor expired at t
+func (k *APIKey) Active(t time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || t.Before(*k.ExpiresAt))
+}
+
+// Store persists API keys. FindByPrefix and FindByUUID return nil, nil when no
+// key matches, like repository.BaseRepository.
+type Store interface {
+	Create(key *APIKey) error
+	FindByPrefix(prefix string) (*APIKey, error)
+	FindByUUID(uuid string) (*APIKey, error)
+	Update(key *APIKey) error
+	UpdateLastUsed(id uint, at time.Time) error
+}
+
+// Service issues and authenticates merchant API keys
+type Service struct {
+	store Store
+	clock func() time.Time
+}
+
+// NewService creates an API key service on top of a store, usually
+// repository.NewAPIKeyRepository
+func NewService(store Store) *Service {
+	return &Service{store: store, clock: time.Now}
+}
+
+// WithClock returns a copy of the service using a different clock, for tests
+func (s *Service) WithClock(clock func() time.Time) *Service {
+	clone := *s
+	clone.clock = clock
+	return &clone
+}
+
+// Create generates a key for a merchant. The plaintext key is only returned here
+// and cannot be recovered later. A nil expiresAt creates a key that never expires.
+func (s *Service) Create(merchantID, name string, scopes []string, expiresAt *time.Time) (string, *APIKey, error) {
+	plaintext, prefix, err := generateKey()
+	if err != nil {
+		return "", nil, err
+	}
+
+	key := &APIKey{
+		UUID:       uuid.NewString(),
+		MerchantID: merchantID,
+		Name:       name,
+		Prefix:     prefix,
+		Hash:       hashKey(plaintext),
+		Scopes:     append([]string(nil), scopes...),
+		ExpiresAt:  expiresAt,
+	}
+	if err := s.store.Create(key); err != nil {
+		return "", nil, err
+	}
+	return plaintext, key, nil
+}
+
+// Authenticate resolves a plaintext key to its stored record and records its use
+func (s *Service) Authenticate(plaintext string) (*APIKey, error) {
+	prefix, ok := parsePrefix(plaintext)
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+
+	key, err := s.store.FindByPrefix(prefix)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil || subtle.ConstantTimeCompare([]byte(key.Hash), []byte(hashKey(plaintext))) != 1 {
+		return nil, ErrInvalidKey
+	}
+
+	now := s.clock()
+	if key.RevokedAt != nil {
+		return nil, ErrKeyRevoked
+	}
+	if !key.Active(now) {
+		return nil, ErrKeyExpired
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= lastUsedInterval {
+		if err := s.store.UpdateLastUsed(key.ID, now); err != nil {
+			return nil, fmt.Errorf("failed to record API key use: %w", err)
+		}
+		key.LastUsedAt = &now
+	}
+	return key, nil
+}
+
+// Rotate issues a replacement for a key with the same merchant, name, scopes and
+// expiry. The old key keeps working for overlap so clients can switch over; an
+// overlap of zero revokes it immediately.
+func (s *Service) Rotate(keyUUID string, overlap time.Duration) (string, *APIKey, error) {
+	old, err := s.find(keyUUID)
+	if err != nil {
+		return "", nil, err
+	}
+	now := s.clock()
+	if !old.Active(now) {
+		return "", nil, ErrKeyExpired
+	}
+
+	plaintext, key, err := s.Create(old.MerchantID, old.Name, old.Scopes, old.ExpiresAt)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if overlap <= 0 {
+		old.RevokedAt = &now
+	} else if until := now.Add(overlap); old.ExpiresAt == nil || until.Before(*old.ExpiresAt) {
+		old.ExpiresAt = &until
+	}
+	if err := s.store.Update(old); err != nil {
+		return "", nil, err
+	}
+	return plaintext, key, nil
+}
+
+// Revoke disables a key immediately
+func (s *Service) Revoke(keyUUID string) error {
+	key, err := s.find(keyUUID)
+	if err != nil {
+		return err
+	}
+	if key.RevokedAt != nil {
+		return nil
+	}
+	now := s.clock()
+	key.RevokedAt = &now
+	return s.store.Update(key)
+}
+
+// find loads a key by UUID
+func (s *Service) find(keyUUID string) (*APIKey, error) {
+	key, err := s.store.FindByUUID(keyUUID)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, ErrKeyNotFound
+	}
+	return key, nil
+}
+
+// generateKey returns a new "pk_<public id>_<secret>" key and its public prefix
+func generateKey() (plaintext, prefix string, err error) {
+	buf := make([]byte, publicIDBytes+secretBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	prefix = KeyPrefix + "_" + hex.EncodeToString(buf[:publicIDBytes])
+	return prefix + "_" + base64.RawURLEncoding.EncodeToString(buf[publicIDBytes:]), prefix, nil
+}
+
+// parsePrefix returns the public prefix of a plaintext key
+func parsePrefix(plaintext string) (string, bool) {
+	parts := strings.SplitN(plaintext, "_", 3)
+	if len(parts) != 3 || parts[0] != KeyPrefix || parts[1] == "" || parts[2] == "" {
+		return "", false
+	}
+	return parts[0] + "_" + parts[1], true
+}
+
+// hashKey hashes a plaintext key. Keys carry 256 bits of entropy, so a fast hash
+// is enough and keeps lookups cheap.
+func hashKey(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:])
+}
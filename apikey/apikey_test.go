@@ -0,0 +1,170 @@
+package apikey
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// memoryStore is an in-memory Store for tests
+type memoryStore struct {
+	keys   []*APIKey
+	nextID uint
+}
+
+func (s *memoryStore) Create(key *APIKey) error {
+	s.nextID++
+	key.ID = s.nextID
+	copied := *key
+	s.keys = append(s.keys, &copied)
+	return nil
+}
+
+func (s *memoryStore) find(match func(*APIKey) bool) (*APIKey, error) {
+	for _, key := range s.keys {
+		if match(key) {
+			copied := *key
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *memoryStore) FindByPrefix(prefix string) (*APIKey, error) {
+	return s.find(func(k *APIKey) bool { return k.Prefix == prefix })
+}
+
+func (s *memoryStore) FindByUUID(uuid string) (*APIKey, error) {
+	return s.find(func(k *APIKey) bool { return k.UUID == uuid })
+}
+
+func (s *memoryStore) Update(key *APIKey) error {
+	for i, k := range s.keys {
+		if k.ID == key.ID {
+			copied := *key
+			s.keys[i] = &copied
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) UpdateLastUsed(id uint, at time.Time) error {
+	for _, k := range s.keys {
+		if k.ID == id {
+			k.LastUsedAt = &at
+		}
+	}
+	return nil
+}
+
+func newTestService(now *time.Time) (*Service, *memoryStore) {
+	store := &memoryStore{}
+	return NewService(store).WithClock(func() time.Time { return *now }), store
+}
+
+func TestCreateAndAuthenticate(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	svc, store := newTestService(&now)
+
+	plaintext, key, err := svc.Create("merchant-1", "Production", []string{"payments.*"}, nil)
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	if !strings.HasPrefix(plaintext, key.Prefix+"_") {
+		t.Errorf("key %q does not start with its prefix %q", plaintext, key.Prefix)
+	}
+	if strings.Contains(store.keys[0].Hash, plaintext) || store.keys[0].Hash == "" {
+		t.Error("expected only the key hash to be stored")
+	}
+
+	authenticated, err := svc.Authenticate(plaintext)
+	if err != nil {
+		t.Fatalf("Authenticate returned error: %v", err)
+	}
+	if authenticated.MerchantID != "merchant-1" || !authenticated.HasScope("payments.create") || authenticated.HasScope("payouts.create") {
+		t.Errorf("unexpected key: %+v", authenticated)
+	}
+	if store.keys[0].LastUsedAt == nil {
+		t.Error("expected last used to be recorded")
+	}
+
+	for _, invalid := range []string{"", "pk_", "sk_abc_def", key.Prefix + "_wrong", plaintext + "x"} {
+		if _, err := svc.Authenticate(invalid); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Authenticate(%q) error = %v, expected %v", invalid, err, ErrInvalidKey)
+		}
+	}
+}
+
+func TestAuthenticate_Expired(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	svc, _ := newTestService(&now)
+
+	expiresAt := now.Add(time.Hour)
+	plaintext, _, err := svc.Create("merchant-1", "Temporary", nil, &expiresAt)
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if _, err := svc.Authenticate(plaintext); !errors.Is(err, ErrKeyExpired) {
+		t.Errorf("Authenticate error = %v, expected %v", err, ErrKeyExpired)
+	}
+}
+
+func TestRotate_Overlap(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	svc, _ := newTestService(&now)
+
+	oldKey, old, err := svc.Create("merchant-1", "Production", []string{"payments.read"}, nil)
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	newKey, rotated, err := svc.Rotate(old.UUID, time.Hour)
+	if err != nil {
+		t.Fatalf("Rotate returned error: %v", err)
+	}
+	if rotated.MerchantID != old.MerchantID || rotated.Prefix == old.Prefix {
+		t.Errorf("unexpected rotated key: %+v", rotated)
+	}
+
+	// Both keys work during the overlap
+	for _, k := range []string{oldKey, newKey} {
+		if _, err := svc.Authenticate(k); err != nil {
+			t.Errorf("Authenticate returned error during overlap: %v", err)
+		}
+	}
+
+	now = now.Add(2 * time.Hour)
+	if _, err := svc.Authenticate(oldKey); !errors.Is(err, ErrKeyExpired) {
+		t.Errorf("Authenticate error = %v, expected %v", err, ErrKeyExpired)
+	}
+	if _, err := svc.Authenticate(newKey); err != nil {
+		t.Errorf("Authenticate returned error for the new key: %v", err)
+	}
+}
+
+func TestRevoke(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	svc, _ := newTestService(&now)
+
+	plaintext, key, _ := svc.Create("merchant-1", "Production", nil, nil)
+	if err := svc.Revoke(key.UUID); err != nil {
+		t.Fatalf("Revoke returned error: %v", err)
+	}
+	if _, err := svc.Authenticate(plaintext); !errors.Is(err, ErrKeyRevoked) {
+		t.Errorf("Authenticate error = %v, expected %v", err, ErrKeyRevoked)
+	}
+	if err := svc.Revoke("unknown"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Revoke error = %v, expected %v", err, ErrKeyNotFound)
+	}
+}
@@ -0,0 +1,40 @@
+package crypto
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// BodyHash returns the lowercase hex SHA-256 of a request body
+func BodyHash(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// RequestStringToSign builds the canonical string signed by SignRequest:
+// METHOD, path with query, timestamp, nonce and body hash joined by newlines
+func RequestStringToSign(method, path, timestamp, nonce string, body []byte) string {
+	return strings.Join([]string{strings.ToUpper(method), path, timestamp, nonce, BodyHash(body)}, "\n")
+}
+
+// SignRequest returns the hex HMAC-SHA256 signature of a request
+func SignRequest(secret []byte, method, path, timestamp, nonce string, body []byte) string {
+	return hex.EncodeToString(requestMAC(secret, method, path, timestamp, nonce, body))
+}
+
+// VerifyRequestSignature checks a hex HMAC-SHA256 request signature in constant time
+func VerifyRequestSignature(secret []byte, signature, method, path, timestamp, nonce string, body []byte) bool {
+	actual, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(requestMAC(secret, method, path, timestamp, nonce, body), actual)
+}
+
+func requestMAC(secret []byte, method, path, timestamp, nonce string, body []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(RequestStringToSign(method, path, timestamp, nonce, body)))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,143 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"strings"
+	"time"
+)
+
+// SnapTimestampFormat is the ISO-8601 layout SNAP BI uses for X-TIMESTAMP,
+// e.g. 2024-01-31T13:45:00+07:00
+const SnapTimestampFormat = "2006-01-02T15:04:05-07:00"
+
+var ErrInvalidPEM = errors.New("invalid PEM key")
+
+// FormatSnapTimestamp formats a time for the X-TIMESTAMP header
+func FormatSnapTimestamp(t time.Time) string {
+	return t.Format(SnapTimestampFormat)
+}
+
+// ParseSnapTimestamp parses an X-TIMESTAMP header
+func ParseSnapTimestamp(value string) (time.Time, error) {
+	return time.Parse(SnapTimestampFormat, value)
+}
+
+// SnapAsymmetricStringToSign builds the string signed for the B2B access token:
+// client key + "|" + X-TIMESTAMP
+func SnapAsymmetricStringToSign(clientKey, timestamp string) string {
+	return clientKey + "|" + timestamp
+}
+
+// SnapAsymmetricSign signs the access token request with SHA256withRSA and
+// returns the base64 signature for X-SIGNATURE
+func SnapAsymmetricSign(privateKey *rsa.PrivateKey, clientKey, timestamp string) (string, error) {
+	digest := sha256.Sum256([]byte(SnapAsymmetricStringToSign(clientKey, timestamp)))
+	signature, err := rsa.SignPKCS1v15(nil, privateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
+
+// SnapAsymmetricVerify verifies a SHA256withRSA access token request signature
+func SnapAsymmetricVerify(publicKey *rsa.PublicKey, signature, clientKey, timestamp string) bool {
+	raw, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	digest := sha256.Sum256([]byte(SnapAsymmetricStringToSign(clientKey, timestamp)))
+	return rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], raw) == nil
+}
+
+// SnapSymmetricStringToSign builds the string signed for transactional requests:
+// METHOD:endpoint:access token:lowercase hex SHA-256 of the minified body:X-TIMESTAMP
+func SnapSymmetricStringToSign(method, endpoint, accessToken string, body []byte, timestamp string) (string, error) {
+	minified, err := MinifyJSON(body)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join([]string{strings.ToUpper(method), endpoint, accessToken, BodyHash(minified), timestamp}, ":"), nil
+}
+
+// SnapSymmetricSign signs a transactional request with HMAC-SHA512 using the client
+// secret and returns the base64 signature for X-SIGNATURE
+func SnapSymmetricSign(clientSecret []byte, method, endpoint, accessToken string, body []byte, timestamp string) (string, error) {
+	stringToSign, err := SnapSymmetricStringToSign(method, endpoint, accessToken, body, timestamp)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha512.New, clientSecret)
+	mac.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// SnapSymmetricVerify verifies an HMAC-SHA512 transactional request signature in constant time
+func SnapSymmetricVerify(clientSecret []byte, signature, method, endpoint, accessToken string, body []byte, timestamp string) bool {
+	expected, err := SnapSymmetricSign(clientSecret, method, endpoint, accessToken, body, timestamp)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// MinifyJSON removes insignificant whitespace from a JSON body. An empty body stays empty.
+func MinifyJSON(body []byte) ([]byte, error) {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil, nil
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, body); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ParseRSAPrivateKeyPEM parses a PKCS#1 or PKCS#8 RSA private key
+func ParseRSAPrivateKeyPEM(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrInvalidPEM
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("not an RSA private key")
+	}
+	return key, nil
+}
+
+// ParseRSAPublicKeyPEM parses a PKIX or PKCS#1 RSA public key
+func ParseRSAPublicKeyPEM(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrInvalidPEM
+	}
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("not an RSA public key")
+	}
+	return key, nil
+}
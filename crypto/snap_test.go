@@ -0,0 +1,76 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+)
+
+func TestSnapAsymmetricSignature(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	timestamp := FormatSnapTimestamp(time.Now())
+
+	signature, err := SnapAsymmetricSign(key, "client-key", timestamp)
+	if err != nil {
+		t.Fatalf("SnapAsymmetricSign returned error: %v", err)
+	}
+	if !SnapAsymmetricVerify(&key.PublicKey, signature, "client-key", timestamp) {
+		t.Error("expected signature to verify")
+	}
+	if SnapAsymmetricVerify(&key.PublicKey, signature, "other-client", timestamp) {
+		t.Error("expected signature for another client key to fail")
+	}
+}
+
+func TestSnapSymmetricSignature(t *testing.T) {
+	secret := []byte("client-secret")
+	timestamp := "2024-01-31T13:45:00+07:00"
+	body := []byte("{\n  \"partnerReferenceNo\": \"2020102900000000000001\",\n  \"amount\": {\"value\": \"12345678.00\", \"currency\": \"IDR\"}\n}")
+
+	stringToSign, err := SnapSymmetricStringToSign("post", "/v1.0/transfer-intrabank", "token", body, timestamp)
+	if err != nil {
+		t.Fatalf("SnapSymmetricStringToSign returned error: %v", err)
+	}
+	minified, _ := MinifyJSON(body)
+	expected := "POST:/v1.0/transfer-intrabank:token:" + BodyHash(minified) + ":" + timestamp
+	if stringToSign != expected {
+		t.Errorf("stringToSign = %q, expected %q", stringToSign, expected)
+	}
+
+	signature, err := SnapSymmetricSign(secret, "POST", "/v1.0/transfer-intrabank", "token", body, timestamp)
+	if err != nil {
+		t.Fatalf("SnapSymmetricSign returned error: %v", err)
+	}
+	// Whitespace in the body does not change the signature
+	if !SnapSymmetricVerify(secret, signature, "POST", "/v1.0/transfer-intrabank", "token", minified, timestamp) {
+		t.Error("expected signature over the minified body to verify")
+	}
+	if SnapSymmetricVerify(secret, signature, "POST", "/v1.0/transfer-intrabank", "other-token", body, timestamp) {
+		t.Error("expected signature with another access token to fail")
+	}
+}
+
+func TestParseRSAKeysPEM(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	pkcs8, _ := x509.MarshalPKCS8PrivateKey(key)
+	pkix, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+
+	if _, err := ParseRSAPrivateKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8})); err != nil {
+		t.Errorf("ParseRSAPrivateKeyPEM returned error: %v", err)
+	}
+	if _, err := ParseRSAPublicKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pkix})); err != nil {
+		t.Errorf("ParseRSAPublicKeyPEM returned error: %v", err)
+	}
+	if _, err := ParseRSAPublicKeyPEM([]byte("not a key")); err != ErrInvalidPEM {
+		t.Errorf("ParseRSAPublicKeyPEM error = %v, expected %v", err, ErrInvalidPEM)
+	}
+}
@@ -0,0 +1,224 @@
+package ipwhitelist
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/netip"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/writdev-alt/portal-api-shared/logger"
+	"github.com/writdev-alt/portal-api-shared/redis"
+)
+
+const (
+	// InvalidationChannel carries the owners whose lists changed, as JSON
+	InvalidationChannel = "ip_whitelist:invalidate"
+
+	defaultCacheTTL = time.Minute
+)
+
+var ErrRedisDisabled = errors.New("redis is not configured")
+
+// Entry is an IP or CIDR a merchant allows. Entries without an API key apply to
+// every key of the merchant.
+type Entry struct {
+	ID          uint           `gorm:"primaryKey" json:"-"`
+	UUID        string         `gorm:"type:char(36);uniqueIndex" json:"uuid"`
+	MerchantID  string         `gorm:"type:varchar(64);index:idx_ip_whitelists_owner" json:"merchantId"`
+	APIKeyID    string         `gorm:"type:char(36);index:idx_ip_whitelists_owner" json:"apiKeyId"` // apikey.APIKey UUID, empty for merchant-wide entries
+	Address     string         `gorm:"type:varchar(64)" json:"address"`                             // IP or CIDR
+	Description string         `gorm:"type:varchar(255)" json:"description"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	UpdatedAt   time.Time      `json:"updatedAt"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// TableName returns the table name for Entry
+func (Entry) TableName() string {
+	return "ip_whitelists"
+}
+
+// Owner identifies a list: a merchant's, or one of its API keys' when APIKeyID is set
+type Owner struct {
+	MerchantID string `json:"merchantId"`
+	APIKeyID   string `json:"apiKeyId,omitempty"`
+}
+
+// String returns a stable key for the owner
+func (o Owner) String() string {
+	if o.APIKeyID != "" {
+		return "merchant:" + o.MerchantID + ":api_key:" + o.APIKeyID
+	}
+	return "merchant:" + o.MerchantID
+}
+
+// Source loads the entries of a list. An owner without a list has no entries.
+type Source interface {
+	Entries(owner Owner) ([]string, error)
+}
+
+// Options configures a Whitelist
+type Options struct {
+	// CacheTTL bounds how long compiled lists are kept when an invalidation is
+	// missed. Defaults to 1 minute.
+	CacheTTL time.Duration
+	// DenyUnlisted rejects merchants without a list instead of allowing every IP
+	DenyUnlisted bool
+}
+
+// cachedTrie is a compiled list
+type cachedTrie struct {
+	trie *Trie
+	// configured is set when the list has entries, even if none of them is valid
+	configured bool
+	expiresAt  time.Time
+}
+
+// Whitelist checks client IPs against per-merchant and per-API-key lists. Lists are
+// compiled into tries on first use and cached until they change (see Listen).
+type Whitelist struct {
+	source Source
+	opts   Options
+
+	mu    sync.RWMutex
+	cache map[Owner]cachedTrie
+}
+
+// New creates a whitelist reading lists from a source
+func New(source Source, opts Options) *Whitelist {
+	if opts.CacheTTL <= 0 {
+		opts.CacheTTL = defaultCacheTTL
+	}
+	return &Whitelist{source: source, opts: opts, cache: make(map[Owner]cachedTrie)}
+}
+
+// Allowed reports whether an IP may act for an owner. An API key with its own list
+// is checked against that list only; otherwise the merchant's list applies. A list
+// whose entries are all invalid denies every IP.
+func (w *Whitelist) Allowed(owner Owner, ip string) (bool, error) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false, nil
+	}
+
+	if owner.APIKeyID != "" {
+		list, err := w.list(owner)
+		if err != nil {
+			return false, err
+		}
+		if list.configured {
+			return list.trie.Contains(addr), nil
+		}
+	}
+
+	list, err := w.list(Owner{MerchantID: owner.MerchantID})
+	if err != nil {
+		return false, err
+	}
+	if !list.configured {
+		return !w.opts.DenyUnlisted, nil
+	}
+	return list.trie.Contains(addr), nil
+}
+
+// Invalidate drops compiled lists from this process. Without owners every list is dropped.
+func (w *Whitelist) Invalidate(owners ...Owner) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(owners) == 0 {
+		w.cache = make(map[Owner]cachedTrie)
+		return
+	}
+	for _, owner := range owners {
+		delete(w.cache, owner)
+	}
+}
+
+// Listen subscribes to InvalidationChannel and applies invalidations published by
+// any instance (see NotifyChanged) in the background until ctx is done
+func (w *Whitelist) Listen(ctx context.Context) error {
+	if !redis.IsEnabled() {
+		return ErrRedisDisabled
+	}
+	pubsub, err := redis.Subscribe(InvalidationChannel)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		defer pubsub.Close()
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				var owner Owner
+				if err := json.Unmarshal([]byte(msg.Payload), &owner); err != nil {
+					logger.Warnf("Invalid IP whitelist invalidation %q: %v", msg.Payload, err)
+					w.Invalidate()
+					continue
+				}
+				w.Invalidate(owner)
+			}
+		}
+	}()
+	return nil
+}
+
+// NotifyChanged tells every instance listening on InvalidationChannel that the
+// lists of owners changed. Call it after editing entries in the database.
+func NotifyChanged(owners ...Owner) error {
+	if !redis.IsEnabled() {
+		return ErrRedisDisabled
+	}
+	for _, owner := range owners {
+		payload, err := json.Marshal(owner)
+		if err != nil {
+			return err
+		}
+		if err := redis.PublishMessage(InvalidationChannel, string(payload)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// list returns the compiled list of an owner, loading it on a cache miss
+func (w *Whitelist) list(owner Owner) (cachedTrie, error) {
+	now := time.Now()
+	w.mu.RLock()
+	cached, ok := w.cache[owner]
+	w.mu.RUnlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached, nil
+	}
+
+	entries, err := w.source.Entries(owner)
+	if err != nil {
+		return cachedTrie{}, err
+	}
+	trie := &Trie{}
+	for _, entry := range entries {
+		// A bad entry must not open the whole list, skip it
+		if err := trie.Insert(entry); err != nil {
+			logger.Warnf("Skipping IP whitelist entry of %s: %v", owner, err)
+		}
+	}
+	if len(entries) > 0 && trie.Len() == 0 {
+		logger.Errorf("IP whitelist of %s has no valid entries, denying every IP", owner)
+	}
+
+	cached = cachedTrie{trie: trie, configured: len(entries) > 0, expiresAt: now.Add(w.opts.CacheTTL)}
+	w.mu.Lock()
+	w.cache[owner] = cached
+	w.mu.Unlock()
+	return cached, nil
+}
@@ -0,0 +1,150 @@
+package ipwhitelist
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+
+	"github.com/writdev-alt/portal-api-shared/redis"
+)
+
+// staticSource is a Source with fixed lists that counts loads
+type staticSource struct {
+	lists map[Owner][]string
+	loads int
+}
+
+func (s *staticSource) Entries(owner Owner) ([]string, error) {
+	s.loads++
+	return s.lists[owner], nil
+}
+
+func TestTrie(t *testing.T) {
+	trie, err := NewTrie("203.0.113.7", "198.51.100.0/24", "2001:db8::/32", "::ffff:192.0.2.0/120")
+	if err != nil {
+		t.Fatalf("NewTrie returned error: %v", err)
+	}
+
+	tests := map[string]bool{
+		"203.0.113.7":        true,
+		"203.0.113.8":        false,
+		"198.51.100.255":     true,
+		"198.51.101.0":       false,
+		"2001:db8:1::1":      true,
+		"2001:db9::1":        false,
+		"192.0.2.10":         true,
+		"::ffff:203.0.113.7": true,
+	}
+	for ip, want := range tests {
+		if got := trie.Contains(netip.MustParseAddr(ip)); got != want {
+			t.Errorf("Contains(%s) = %v, want %v", ip, got, want)
+		}
+	}
+
+	if _, err := NewTrie("not-an-ip"); err == nil {
+		t.Error("expected an error for an invalid entry")
+	}
+}
+
+func TestWhitelist_Allowed(t *testing.T) {
+	merchant := Owner{MerchantID: "m-1"}
+	key := Owner{MerchantID: "m-1", APIKeyID: "key-1"}
+	source := &staticSource{lists: map[Owner][]string{
+		merchant: {"198.51.100.0/24"},
+		key:      {"203.0.113.7", "bogus"},
+	}}
+	w := New(source, Options{})
+
+	tests := []struct {
+		owner Owner
+		ip    string
+		want  bool
+	}{
+		{merchant, "198.51.100.9", true},
+		{merchant, "203.0.113.7", false},
+		{key, "203.0.113.7", true},
+		{key, "198.51.100.9", false}, // The key's own list replaces the merchant's
+		{Owner{MerchantID: "m-1", APIKeyID: "key-2"}, "198.51.100.9", true},
+		{Owner{MerchantID: "m-2"}, "192.0.2.1", true}, // No list
+		{merchant, "invalid", false},
+	}
+	for _, tt := range tests {
+		allowed, err := w.Allowed(tt.owner, tt.ip)
+		if err != nil || allowed != tt.want {
+			t.Errorf("Allowed(%s, %s) = %v, %v, want %v", tt.owner, tt.ip, allowed, err, tt.want)
+		}
+	}
+
+	strict := New(source, Options{DenyUnlisted: true})
+	if allowed, _ := strict.Allowed(Owner{MerchantID: "m-2"}, "192.0.2.1"); allowed {
+		t.Error("expected merchants without a list to be denied")
+	}
+}
+
+func TestWhitelist_AllowedOnlyInvalidEntries(t *testing.T) {
+	merchant := Owner{MerchantID: "m-1"}
+	key := Owner{MerchantID: "m-1", APIKeyID: "key-1"}
+	broken := Owner{MerchantID: "m-2"}
+	source := &staticSource{lists: map[Owner][]string{
+		merchant: {"198.51.100.0/24"},
+		key:      {"bogus", "10.0.0.0/33"},
+		broken:   {"not-an-ip"},
+	}}
+	w := New(source, Options{})
+
+	tests := []struct {
+		owner Owner
+		ip    string
+	}{
+		{broken, "192.0.2.1"},
+		{key, "198.51.100.9"}, // Does not fall back to the merchant's list
+		{key, "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		allowed, err := w.Allowed(tt.owner, tt.ip)
+		if err != nil || allowed {
+			t.Errorf("Allowed(%s, %s) = %v, %v, want false", tt.owner, tt.ip, allowed, err)
+		}
+	}
+}
+
+func TestWhitelist_Listen(t *testing.T) {
+	mr := miniredis.RunT(t)
+	redis.SetClient(goredis.NewClient(&goredis.Options{Addr: mr.Addr()}))
+	t.Cleanup(func() { redis.SetClient(nil) })
+
+	owner := Owner{MerchantID: "m-1"}
+	source := NewRedisSource()
+	if err := source.Add(owner, "198.51.100.0/24"); err != nil {
+		t.Fatalf("Add returned error: %v", err)
+	}
+
+	w := New(source, Options{CacheTTL: time.Hour})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := w.Listen(ctx); err != nil {
+		t.Fatalf("Listen returned error: %v", err)
+	}
+
+	if allowed, _ := w.Allowed(owner, "203.0.113.7"); allowed {
+		t.Fatal("expected the IP to be denied before it was added")
+	}
+	if err := source.Add(owner, "203.0.113.7"); err != nil {
+		t.Fatalf("Add returned error: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if allowed, _ := w.Allowed(owner, "203.0.113.7"); allowed {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the invalidation to reload the list")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,65 @@
+package ipwhitelist
+
+import (
+	"github.com/writdev-alt/portal-api-shared/redis"
+)
+
+const redisKeyPrefix = "ip_whitelist:"
+
+// RedisSource is a Source keeping each list in a Redis set
+type RedisSource struct{}
+
+// NewRedisSource creates a source backed by the package-level Redis client
+func NewRedisSource() *RedisSource {
+	return &RedisSource{}
+}
+
+// Entries implements Source
+func (s *RedisSource) Entries(owner Owner) ([]string, error) {
+	if !redis.IsEnabled() {
+		return nil, ErrRedisDisabled
+	}
+	return redis.SMembers(redisKeyPrefix + owner.String())
+}
+
+// Add validates and adds entries to a list, then notifies every instance
+func (s *RedisSource) Add(owner Owner, entries ...string) error {
+	if !redis.IsEnabled() {
+		return ErrRedisDisabled
+	}
+	members := make([]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		prefix, err := ParseEntry(entry)
+		if err != nil {
+			return err
+		}
+		members = append(members, prefix.String())
+	}
+	if len(members) == 0 {
+		return nil
+	}
+	if err := redis.SAdd(redisKeyPrefix+owner.String(), members...); err != nil {
+		return err
+	}
+	return NotifyChanged(owner)
+}
+
+// Remove removes entries from a list, then notifies every instance
+func (s *RedisSource) Remove(owner Owner, entries ...string) error {
+	if !redis.IsEnabled() {
+		return ErrRedisDisabled
+	}
+	members := make([]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		if prefix, err := ParseEntry(entry); err == nil {
+			members = append(members, prefix.String())
+		}
+	}
+	if len(members) == 0 {
+		return nil
+	}
+	if err := redis.SRem(redisKeyPrefix+owner.String(), members...); err != nil {
+		return err
+	}
+	return NotifyChanged(owner)
+}
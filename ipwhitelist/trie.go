@@ -0,0 +1,110 @@
+package ipwhitelist
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// trieNode is a node of a binary trie over address bits
+type trieNode struct {
+	children [2]*trieNode
+	terminal bool // A prefix ends here
+}
+
+// Trie is a set of IPs and CIDRs with lookups in O(address bits). IPv4 and
+// IPv4-mapped IPv6 addresses are treated alike.
+type Trie struct {
+	v4, v6 trieNode
+	size   int
+}
+
+// NewTrie builds a trie from IPs ("203.0.113.7") and CIDRs ("203.0.113.0/24")
+func NewTrie(entries ...string) (*Trie, error) {
+	t := &Trie{}
+	for _, entry := range entries {
+		if err := t.Insert(entry); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// ParseEntry parses an IP or CIDR into a masked prefix
+func ParseEntry(entry string) (netip.Prefix, error) {
+	entry = strings.TrimSpace(entry)
+	if strings.Contains(entry, "/") {
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("invalid CIDR %q: %w", entry, err)
+		}
+		if prefix.Addr().Is4In6() && prefix.Bits() >= 96 {
+			prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+		}
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(entry)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid IP %q: %w", entry, err)
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// Insert adds an IP or CIDR
+func (t *Trie) Insert(entry string) error {
+	prefix, err := ParseEntry(entry)
+	if err != nil {
+		return err
+	}
+
+	node := t.root(prefix.Addr())
+	bytes := prefix.Addr().AsSlice()
+	for i := 0; i < prefix.Bits(); i++ {
+		bit := bytes[i/8] >> (7 - i%8) & 1
+		if node.children[bit] == nil {
+			node.children[bit] = &trieNode{}
+		}
+		node = node.children[bit]
+	}
+	if !node.terminal {
+		node.terminal = true
+		t.size++
+	}
+	return nil
+}
+
+// Contains reports whether an address falls within any inserted IP or CIDR
+func (t *Trie) Contains(addr netip.Addr) bool {
+	if !addr.IsValid() {
+		return false
+	}
+	addr = addr.Unmap().WithZone("")
+
+	node := t.root(addr)
+	bytes := addr.AsSlice()
+	for i := 0; ; i++ {
+		if node.terminal {
+			return true
+		}
+		if i == addr.BitLen() {
+			return false
+		}
+		node = node.children[bytes[i/8]>>(7-i%8)&1]
+		if node == nil {
+			return false
+		}
+	}
+}
+
+// Len returns the number of distinct entries
+func (t *Trie) Len() int {
+	return t.size
+}
+
+func (t *Trie) root(addr netip.Addr) *trieNode {
+	if addr.Is4() {
+		return &t.v4
+	}
+	return &t.v6
+}
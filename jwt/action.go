@@ -0,0 +1,138 @@
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TokenTypeAction marks single-purpose tokens issued by IssueActionToken
+const TokenTypeAction TokenType = "action"
+
+// ActionPurpose is the one action an action token authorizes
+type ActionPurpose string
+
+const (
+	PurposeEmailVerification ActionPurpose = "email_verification"
+	PurposePasswordReset     ActionPurpose = "password_reset"
+	PurposeStepUp            ActionPurpose = "step_up"
+)
+
+const defaultActionTokenExpiry = 15 * time.Minute
+
+var (
+	ErrActionTokenUsed   = errors.New("action token has already been used")
+	ErrPurposeMismatch   = errors.New("action token purpose mismatch")
+	ErrTargetMismatch    = errors.New("action token target mismatch")
+	ErrInvalidActionData = errors.New("action token is missing its purpose or nonce")
+)
+
+// Action binds a token to a purpose, the resource it acts on and a one-time nonce
+type Action struct {
+	Purpose ActionPurpose `json:"purpose"`
+	// Target is the resource the action applies to, e.g. an email address or a withdrawal ID
+	Target string `json:"target,omitempty"`
+	Nonce  string `json:"nonce"`
+}
+
+// ActionClaims represents the claims of an action token
+type ActionClaims = CustomClaims[Action]
+
+// IssueActionToken issues a short-lived token that authorizes a single action on
+// target for a user. Its nonce is stored in Redis and consumed by VerifyActionToken,
+// so the token can only be used once. An expiry of zero defaults to 15 minutes.
+func (m *Manager) IssueActionToken(userID uuid.UUID, purpose ActionPurpose, target string, expiry time.Duration) (string, error) {
+	store := m.tokenStore()
+	if store == nil {
+		return "", ErrRedisNotEnabled
+	}
+	if expiry <= 0 {
+		expiry = defaultActionTokenExpiry
+	}
+
+	action := Action{Purpose: purpose, Target: target, Nonce: uuid.NewString()}
+	claims := newCustomClaims(m, TokenTypeAction, Identity{UUID: userID}, action, expiry)
+	token, err := m.sign(claims)
+	if err != nil {
+		return "", err
+	}
+
+	// The nonce outlives the token by the leeway so it cannot expire before the token does
+	if err := store.StoreActionNonce(action.Nonce, string(purpose), expiry+m.leeway); err != nil {
+		return "", fmt.Errorf("failed to store action nonce: %w", err)
+	}
+	return token, nil
+}
+
+// VerifyActionToken validates an action token for purpose and consumes its nonce.
+// The token must have been issued for target. Failures map to CaseCodeTokenExpired
+// or CaseCodeInvalidToken through CaseCode.
+func (m *Manager) VerifyActionToken(tokenString string, purpose ActionPurpose, target string) (*ActionClaims, error) {
+	return m.verifyActionToken(tokenString, purpose, target)
+}
+
+// VerifyUntargetedActionToken is VerifyActionToken for tokens issued without a
+// target, such as step-up tokens. Tokens bound to a target are rejected.
+func (m *Manager) VerifyUntargetedActionToken(tokenString string, purpose ActionPurpose) (*ActionClaims, error) {
+	return m.verifyActionToken(tokenString, purpose, "")
+}
+
+// verifyActionToken checks the purpose and the exact target, then consumes the nonce
+func (m *Manager) verifyActionToken(tokenString string, purpose ActionPurpose, target string) (*ActionClaims, error) {
+	claims, err := parseClaims[Action](m, tokenString, TokenTypeAction)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Custom.Purpose == "" || claims.Custom.Nonce == "" {
+		return nil, ErrInvalidActionData
+	}
+	if claims.Custom.Purpose != purpose {
+		return nil, ErrPurposeMismatch
+	}
+	if claims.Custom.Target != target {
+		return nil, ErrTargetMismatch
+	}
+	store := m.tokenStore()
+	if store == nil {
+		return nil, ErrRedisNotEnabled
+	}
+
+	consumed, err := store.ConsumeActionNonce(claims.Custom.Nonce, string(purpose))
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume action nonce: %w", err)
+	}
+	if !consumed {
+		return nil, ErrActionTokenUsed
+	}
+	return claims, nil
+}
+
+// IssueActionToken issues an action token with the package-level manager
+func IssueActionToken(userID uuid.UUID, purpose ActionPurpose, target string, expiry time.Duration) (string, error) {
+	m, err := Default()
+	if err != nil {
+		return "", err
+	}
+	return m.IssueActionToken(userID, purpose, target, expiry)
+}
+
+// VerifyActionToken verifies and consumes an action token with the package-level manager
+func VerifyActionToken(tokenString string, purpose ActionPurpose, target string) (*ActionClaims, error) {
+	m, err := Default()
+	if err != nil {
+		return nil, err
+	}
+	return m.VerifyActionToken(tokenString, purpose, target)
+}
+
+// VerifyUntargetedActionToken verifies and consumes an untargeted action token with
+// the package-level manager
+func VerifyUntargetedActionToken(tokenString string, purpose ActionPurpose) (*ActionClaims, error) {
+	m, err := Default()
+	if err != nil {
+		return nil, err
+	}
+	return m.VerifyUntargetedActionToken(tokenString, purpose)
+}
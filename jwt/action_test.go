@@ -0,0 +1,122 @@
+package jwt
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	response "github.com/writdev-alt/portal-api-shared/responses"
+)
+
+func TestIssueActionToken_RequiresRedis(t *testing.T) {
+	// Not parallel: relies on the shared Redis client being unset
+	m := newTestManager(t, Options{})
+	_, err := m.IssueActionToken(uuid.New(), PurposePasswordReset, "test@example.com", 0)
+	if !errors.Is(err, ErrRedisNotEnabled) {
+		t.Errorf("IssueActionToken error = %v, expected %v", err, ErrRedisNotEnabled)
+	}
+}
+
+func TestVerifyActionToken(t *testing.T) {
+	t.Parallel()
+
+	m := newTestManager(t, Options{Redis: newTestRedis(t)})
+	userID := uuid.New()
+
+	token, err := m.IssueActionToken(userID, PurposePasswordReset, "test@example.com", 0)
+	if err != nil {
+		t.Fatalf("IssueActionToken returned error: %v", err)
+	}
+
+	claims, err := m.VerifyActionToken(token, PurposePasswordReset, "test@example.com")
+	if err != nil {
+		t.Fatalf("VerifyActionToken returned error: %v", err)
+	}
+	if claims.UUID != userID.String() || claims.Custom.Target != "test@example.com" {
+		t.Errorf("unexpected claims: %+v", claims)
+	}
+
+	// The nonce is consumed by the first verification
+	_, err = m.VerifyActionToken(token, PurposePasswordReset, "test@example.com")
+	if !errors.Is(err, ErrActionTokenUsed) {
+		t.Errorf("VerifyActionToken error = %v, expected %v", err, ErrActionTokenUsed)
+	}
+	if CaseCode(err) != response.CaseCodeInvalidToken {
+		t.Errorf("CaseCode = %s, expected %s", CaseCode(err), response.CaseCodeInvalidToken)
+	}
+}
+
+func TestVerifyActionToken_Mismatch(t *testing.T) {
+	t.Parallel()
+
+	m := newTestManager(t, Options{Redis: newTestRedis(t)})
+
+	token, err := m.IssueActionToken(uuid.New(), PurposeEmailVerification, "test@example.com", 0)
+	if err != nil {
+		t.Fatalf("IssueActionToken returned error: %v", err)
+	}
+
+	if _, err := m.VerifyActionToken(token, PurposePasswordReset, "test@example.com"); !errors.Is(err, ErrPurposeMismatch) {
+		t.Errorf("VerifyActionToken error = %v, expected %v", err, ErrPurposeMismatch)
+	}
+	if _, err := m.VerifyActionToken(token, PurposeEmailVerification, "other@example.com"); !errors.Is(err, ErrTargetMismatch) {
+		t.Errorf("VerifyActionToken error = %v, expected %v", err, ErrTargetMismatch)
+	}
+	if _, err := m.VerifyActionToken(token, PurposeEmailVerification, ""); !errors.Is(err, ErrTargetMismatch) {
+		t.Errorf("VerifyActionToken with empty target error = %v, expected %v", err, ErrTargetMismatch)
+	}
+	if _, err := m.VerifyUntargetedActionToken(token, PurposeEmailVerification); !errors.Is(err, ErrTargetMismatch) {
+		t.Errorf("VerifyUntargetedActionToken error = %v, expected %v", err, ErrTargetMismatch)
+	}
+
+	// Rejected attempts do not consume the nonce
+	if _, err := m.VerifyActionToken(token, PurposeEmailVerification, "test@example.com"); err != nil {
+		t.Errorf("VerifyActionToken returned error: %v", err)
+	}
+
+	// Action tokens are never accepted as access tokens
+	if _, err := m.ValidateToken(token); !errors.Is(err, ErrWrongTokenType) {
+		t.Errorf("ValidateToken error = %v, expected %v", err, ErrWrongTokenType)
+	}
+}
+
+func TestVerifyActionToken_Expired(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	m := newTestManager(t, Options{Clock: func() time.Time { return now }, Redis: newTestRedis(t)})
+
+	token, err := m.IssueActionToken(uuid.New(), PurposeStepUp, "", time.Minute)
+	if err != nil {
+		t.Fatalf("IssueActionToken returned error: %v", err)
+	}
+
+	now = now.Add(time.Hour)
+	_, err = m.VerifyUntargetedActionToken(token, PurposeStepUp)
+	if CaseCode(err) != response.CaseCodeTokenExpired {
+		t.Errorf("CaseCode = %s, expected %s (err = %v)", CaseCode(err), response.CaseCodeTokenExpired, err)
+	}
+}
+
+func TestVerifyUntargetedActionToken(t *testing.T) {
+	t.Parallel()
+
+	m := newTestManager(t, Options{Redis: newTestRedis(t)})
+
+	token, err := m.IssueActionToken(uuid.New(), PurposeStepUp, "", 0)
+	if err != nil {
+		t.Fatalf("IssueActionToken returned error: %v", err)
+	}
+
+	if _, err := m.VerifyActionToken(token, PurposeStepUp, "test@example.com"); !errors.Is(err, ErrTargetMismatch) {
+		t.Errorf("VerifyActionToken error = %v, expected %v", err, ErrTargetMismatch)
+	}
+	if _, err := m.VerifyUntargetedActionToken(token, PurposeStepUp); err != nil {
+		t.Errorf("VerifyUntargetedActionToken returned error: %v", err)
+	}
+	if _, err := m.VerifyUntargetedActionToken(token, PurposeStepUp); !errors.Is(err, ErrActionTokenUsed) {
+		t.Errorf("VerifyUntargetedActionToken error = %v, expected %v", err, ErrActionTokenUsed)
+	}
+}
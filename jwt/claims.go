@@ -0,0 +1,160 @@
+package jwt
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// CustomClaims represents JWT claims with a service specific payload T,
+// e.g. feature flags or a wallet ID, carried in the "ctx" claim.
+type CustomClaims[T any] struct {
+	UUID        string   `json:"uuid"`
+	Email       string   `json:"email"`
+	Name        string   `json:"name"`
+	Roles       []string `json:"roles,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+	MerchantID  string   `json:"merchant_id,omitempty"`
+	SessionID   string   `json:"sid,omitempty"`
+	// TokenType tells access, refresh and other tokens apart
+	TokenType TokenType `json:"typ,omitempty"`
+	// FamilyID links a refresh token to its rotation family
+	FamilyID string `json:"fid,omitempty"`
+	// IssuedAtNano is the issue time in nanoseconds. iat only has second
+	// precision, which is too coarse to order a token against a revocation
+	// watermark set in the same second
+	IssuedAtNano int64 `json:"iat_ns,omitempty"`
+	Custom       T     `json:"ctx,omitzero"`
+	jwt.RegisteredClaims
+}
+
+// Claims represents JWT claims without a service specific payload
+type Claims = CustomClaims[struct{}]
+
+// HasRole reports whether the claims carry a role
+func (c *CustomClaims[T]) HasRole(role string) bool {
+	return slices.Contains(c.Roles, role)
+}
+
+// HasPermission reports whether the claims carry a permission
+func (c *CustomClaims[T]) HasPermission(permission string) bool {
+	return slices.Contains(c.Permissions, permission)
+}
+
+// Identity describes the principal a token is issued for
+type Identity struct {
+	UUID        uuid.UUID
+	Email       string
+	Name        string
+	Roles       []string
+	Permissions []string
+	MerchantID  string
+	SessionID   string
+}
+
+// identity returns the principal the claims were issued for
+func (c *CustomClaims[T]) identity() (Identity, error) {
+	id, err := uuid.Parse(c.UUID)
+	if err != nil {
+		return Identity{}, err
+	}
+	return Identity{
+		UUID:        id,
+		Email:       c.Email,
+		Name:        c.Name,
+		Roles:       c.Roles,
+		Permissions: c.Permissions,
+		MerchantID:  c.MerchantID,
+		SessionID:   c.SessionID,
+	}, nil
+}
+
+// IssueAccessToken issues an access token for an identity
+func (m *Manager) IssueAccessToken(identity Identity) (string, error) {
+	return m.sign(m.newClaims(TokenTypeAccess, identity, m.accessTokenExpiry))
+}
+
+// IssueCustomAccessToken issues an access token carrying a service specific payload
+func IssueCustomAccessToken[T any](m *Manager, identity Identity, custom T) (string, error) {
+	return m.sign(newCustomClaims(m, TokenTypeAccess, identity, custom, m.accessTokenExpiry))
+}
+
+// ValidateCustomAccessToken validates an access token and decodes its service specific payload
+func ValidateCustomAccessToken[T any](m *Manager, tokenString string) (*CustomClaims[T], error) {
+	return parseClaims[T](m, tokenString, TokenTypeAccess)
+}
+
+// newClaims builds claims without a service specific payload
+func (m *Manager) newClaims(tokenType TokenType, identity Identity, expiry time.Duration) Claims {
+	return newCustomClaims(m, tokenType, identity, struct{}{}, expiry)
+}
+
+// newCustomClaims builds the claims shared by every token issued for a principal, with a unique jti
+func newCustomClaims[T any](m *Manager, tokenType TokenType, identity Identity, custom T, expiry time.Duration) CustomClaims[T] {
+	now := m.now()
+	claims := CustomClaims[T]{
+		UUID:         identity.UUID.String(),
+		Email:        identity.Email,
+		Name:         identity.Name,
+		Roles:        identity.Roles,
+		Permissions:  identity.Permissions,
+		MerchantID:   identity.MerchantID,
+		SessionID:    identity.SessionID,
+		TokenType:    tokenType,
+		IssuedAtNano: now.UnixNano(),
+		Custom:       custom,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Issuer:    m.issuer,
+			ExpiresAt: jwt.NewNumericDate(now.Add(expiry)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now.Add(-m.leeway)),
+		},
+	}
+	if len(m.audience) > 0 {
+		claims.Audience = jwt.ClaimStrings(m.audience)
+	}
+	return claims
+}
+
+// parseClaims verifies the signature, registered claims and revocation state of a
+// token. An empty tokenType accepts tokens of any type.
+func parseClaims[T any](m *Manager, tokenString string, tokenType TokenType) (*CustomClaims[T], error) {
+	claims := &CustomClaims[T]{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, m.keyFunc, m.parserOptions()...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check if token is valid
+	if !token.Valid {
+		return nil, errors.New("token is not valid")
+	}
+
+	var issuedAt time.Time
+	if claims.IssuedAtNano != 0 {
+		issuedAt = time.Unix(0, claims.IssuedAtNano)
+	} else if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time
+	}
+	if err := m.checkRevocation(claims.ID, issuedAt, claims.UUID, claims.MerchantID); err != nil {
+		return nil, err
+	}
+
+	if tokenType != "" {
+		actual := claims.TokenType
+		// Tokens issued before typ was introduced are treated as access tokens until
+		// they expire, so they are never accepted as refresh tokens
+		if actual == "" {
+			actual = TokenTypeAccess
+		}
+		if actual != tokenType {
+			return nil, ErrWrongTokenType
+		}
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,129 @@
+package jwt
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+type walletContext struct {
+	WalletID string `json:"wallet_id"`
+	Tier     int    `json:"tier"`
+}
+
+func TestIssueAccessToken_Identity(t *testing.T) {
+	t.Parallel()
+
+	m := newTestManager(t, Options{})
+	identity := Identity{
+		UUID:        uuid.New(),
+		Email:       "test@example.com",
+		Name:        "Test User",
+		Roles:       []string{"admin", "finance"},
+		Permissions: []string{"payouts:read"},
+		MerchantID:  "merchant-1",
+		SessionID:   "session-1",
+	}
+
+	token, err := m.IssueAccessToken(identity)
+	if err != nil {
+		t.Fatalf("IssueAccessToken returned error: %v", err)
+	}
+
+	claims, err := m.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("ValidateToken returned error: %v", err)
+	}
+	if claims.UUID != identity.UUID.String() || claims.MerchantID != "merchant-1" || claims.SessionID != "session-1" {
+		t.Errorf("unexpected claims: %+v", claims)
+	}
+	if !slices.Equal(claims.Roles, identity.Roles) || !slices.Equal(claims.Permissions, identity.Permissions) {
+		t.Errorf("Roles = %v, Permissions = %v", claims.Roles, claims.Permissions)
+	}
+	if !claims.HasRole("finance") || claims.HasRole("support") {
+		t.Error("HasRole returned unexpected result")
+	}
+	if !claims.HasPermission("payouts:read") || claims.HasPermission("payouts:write") {
+		t.Error("HasPermission returned unexpected result")
+	}
+}
+
+func TestIssueCustomAccessToken(t *testing.T) {
+	t.Parallel()
+
+	m := newTestManager(t, Options{})
+	custom := walletContext{WalletID: "wallet-1", Tier: 2}
+
+	token, err := IssueCustomAccessToken(m, Identity{UUID: uuid.New(), Email: "test@example.com"}, custom)
+	if err != nil {
+		t.Fatalf("IssueCustomAccessToken returned error: %v", err)
+	}
+
+	claims, err := ValidateCustomAccessToken[walletContext](m, token)
+	if err != nil {
+		t.Fatalf("ValidateCustomAccessToken returned error: %v", err)
+	}
+	if claims.Custom != custom {
+		t.Errorf("Custom = %+v, expected %+v", claims.Custom, custom)
+	}
+
+	// Services that do not know the payload can still validate the token
+	if _, err := m.ValidateToken(token); err != nil {
+		t.Errorf("ValidateToken returned error: %v", err)
+	}
+}
+
+func TestRotateRefreshToken_KeepsIdentity(t *testing.T) {
+	setupRedisTest(t)
+	m := newTestManager(t, Options{})
+
+	pair, err := m.IssueTokenPairFor(Identity{
+		UUID:       uuid.New(),
+		Email:      "test@example.com",
+		Roles:      []string{"merchant"},
+		MerchantID: "merchant-1",
+	})
+	if err != nil {
+		t.Fatalf("IssueTokenPairFor returned error: %v", err)
+	}
+	first, err := m.ValidateToken(pair.AccessToken)
+	if err != nil {
+		t.Fatalf("ValidateToken returned error: %v", err)
+	}
+	if first.SessionID == "" {
+		t.Error("expected a session ID")
+	}
+
+	rotated, err := m.RotateRefreshToken(pair.RefreshToken)
+	if err != nil {
+		t.Fatalf("RotateRefreshToken returned error: %v", err)
+	}
+	claims, err := m.ValidateToken(rotated.AccessToken)
+	if err != nil {
+		t.Fatalf("ValidateToken returned error: %v", err)
+	}
+	if claims.MerchantID != "merchant-1" || !claims.HasRole("merchant") || claims.SessionID != first.SessionID {
+		t.Errorf("identity not carried over on rotation: %+v", claims)
+	}
+}
+
+func TestRevokeMerchantTokens(t *testing.T) {
+	setupRedisTest(t)
+	m := newTestManager(t, Options{})
+
+	scoped, _ := m.IssueAccessToken(Identity{UUID: uuid.New(), MerchantID: "merchant-1"})
+	other, _ := m.IssueAccessToken(Identity{UUID: uuid.New(), MerchantID: "merchant-2"})
+
+	if err := m.RevokeMerchantTokens("merchant-1"); err != nil {
+		t.Fatalf("RevokeMerchantTokens returned error: %v", err)
+	}
+
+	if _, err := m.ValidateToken(scoped); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("ValidateToken error = %v, expected %v", err, ErrTokenRevoked)
+	}
+	if _, err := m.ValidateToken(other); err != nil {
+		t.Errorf("ValidateToken returned error for another merchant: %v", err)
+	}
+}
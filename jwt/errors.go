@@ -0,0 +1,25 @@
+package jwt
+
+import (
+	"errors"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	response "github.com/writdev-alt/portal-api-shared/responses"
+)
+
+// CaseCode maps a token error to the response case code returned to clients
+func CaseCode(err error) string {
+	switch {
+	case err == nil:
+		return response.CaseCodeSuccess
+	case errors.Is(err, ErrRefreshTokenReused), errors.Is(err, ErrSessionExpired), errors.Is(err, ErrTokenRevoked):
+		return response.CaseCodeSessionExpired
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return response.CaseCodeTokenExpired
+	case errors.Is(err, ErrRedisNotEnabled), errors.Is(err, ErrRevocationCheckFailed):
+		return response.CaseCodeServiceUnavailable
+	default:
+		return response.CaseCodeInvalidToken
+	}
+}
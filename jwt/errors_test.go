@@ -0,0 +1,26 @@
+package jwt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	response "github.com/writdev-alt/portal-api-shared/responses"
+)
+
+func TestCaseCode(t *testing.T) {
+	setupJWTTest()
+	defer teardownJWTTest()
+
+	expired, _ := GenerateTokenWithExpiry(uuid.New(), "test@example.com", "Test User", -time.Hour)
+	_, err := ValidateToken(expired)
+	if CaseCode(err) != response.CaseCodeTokenExpired {
+		t.Errorf("CaseCode(expired) = %s, expected %s", CaseCode(err), response.CaseCodeTokenExpired)
+	}
+
+	_, err = ValidateToken("invalid.token.string")
+	if CaseCode(err) != response.CaseCodeInvalidToken {
+		t.Errorf("CaseCode(invalid) = %s, expected %s", CaseCode(err), response.CaseCodeInvalidToken)
+	}
+}
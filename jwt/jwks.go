@@ -0,0 +1,29 @@
+package jwt
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKSHandler serves the public keys of the manager's key set as a JWKS document.
+// Mount it at /.well-known/jwks.json so downstream services can verify tokens
+// without holding any signing material.
+func (m *Manager) JWKSHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, m.keys.JWKS())
+	}
+}
+
+// JWKSHandler serves the key set of the package-level manager
+func JWKSHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		m, err := Default()
+		if err != nil {
+			c.JSON(http.StatusOK, JWKS{Keys: []JWK{}})
+			return
+		}
+		m.JWKSHandler()(c)
+	}
+}
@@ -1,137 +1,130 @@
 package jwt
 
 import (
-	"errors"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
 var (
-	jwtSecret          []byte
-	accessTokenExpiry  time.Duration
-	refreshTokenExpiry time.Duration
+	defaultMu      sync.RWMutex
+	defaultManager *Manager
 )
 
-// Claims represents JWT claims
-type Claims struct {
-	UUID  string `json:"uuid"`
-	Email string `json:"email"`
-	Name  string `json:"name"`
-	jwt.RegisteredClaims
+// Default returns the package-level manager used by the package functions,
+// building it from the environment (see OptionsFromEnv) on first use.
+func Default() (*Manager, error) {
+	defaultMu.RLock()
+	m := defaultManager
+	defaultMu.RUnlock()
+	if m != nil {
+		return m, nil
+	}
+
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	if defaultManager == nil {
+		m, err := NewManagerFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		defaultManager = m
+	}
+	return defaultManager, nil
 }
 
-// Init initializes JWT with secret from environment variables.
-//
-// Required:
-// - JWT_SECRET
+// SetDefault replaces the package-level manager. Passing nil makes the next
+// call reload the configuration from the environment.
+func SetDefault(m *Manager) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultManager = m
+}
+
+// Init initializes the package-level manager from environment variables and
+// panics if the configuration is invalid. See OptionsFromEnv for the variables.
 //
-// Optional:
-// - JWT_ACCESS_TOKEN_EXPIRY (Go duration string, e.g. "1h", "30m") OR JWT_ACCESS_TOKEN_EXPIRY_HOURS (int)
-// - JWT_REFRESH_TOKEN_EXPIRY (Go duration string, e.g. "168h") OR JWT_REFRESH_TOKEN_EXPIRY_DAYS (int)
+// Deprecated: the package functions load the configuration lazily and return
+// errors instead of panicking. Use NewManagerFromEnv for an explicit manager.
 func Init() {
-	secret := os.Getenv("JWT_SECRET")
-	if strings.TrimSpace(secret) == "" {
-		panic("JWT_SECRET is not configured")
+	m, err := NewManagerFromEnv()
+	if err != nil {
+		panic(err.Error())
 	}
-	jwtSecret = []byte(secret)
-
-	accessTokenExpiry = parseDurationOrHours("JWT_ACCESS_TOKEN_EXPIRY", "JWT_ACCESS_TOKEN_EXPIRY_HOURS", time.Hour)
-	refreshTokenExpiry = parseDurationOrDays("JWT_REFRESH_TOKEN_EXPIRY", "JWT_REFRESH_TOKEN_EXPIRY_DAYS", 7*24*time.Hour)
+	SetDefault(m)
 }
 
 // GetSecret returns the JWT secret for debugging/verification purposes
-// WARNING: Only use this for debugging. Never expose in production responses.
+//
+// Deprecated: the secret should never leave the manager. This will be removed.
 func GetSecret() string {
-	if len(jwtSecret) == 0 {
-		Init()
+	m, err := Default()
+	if err != nil {
+		return ""
 	}
-	return string(jwtSecret)
+	return string(m.secret)
 }
 
 // GetAccessTokenExpiry returns the access token expiry duration
 func GetAccessTokenExpiry() time.Duration {
-	if accessTokenExpiry == 0 {
-		Init()
+	m, err := Default()
+	if err != nil {
+		return defaultAccessTokenExpiry
+	}
+	return m.AccessTokenExpiry()
+}
+
+// GetKeySet returns the key set of the package-level manager
+func GetKeySet() *KeySet {
+	m, err := Default()
+	if err != nil {
+		return nil
 	}
-	return accessTokenExpiry
+	return m.KeySet()
 }
 
 // GenerateToken generates a JWT token for admin
 func GenerateToken(id uuid.UUID, email, name string) (string, error) {
-	return GenerateTokenWithExpiry(id, email, name, accessTokenExpiry) // Default: 1 hour
+	m, err := Default()
+	if err != nil {
+		return "", err
+	}
+	return m.GenerateToken(id, email, name)
 }
 
 // GenerateTokenWithExpiry generates a JWT token with custom expiration time
 func GenerateTokenWithExpiry(id uuid.UUID, email, name string, expiry time.Duration) (string, error) {
-	if len(jwtSecret) == 0 {
-		Init()
-	}
-
-	claims := Claims{
-		UUID:  id.String(),
-		Email: email,
-		Name:  name,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiry)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now().Add(-30 * time.Second)),
-		},
+	m, err := Default()
+	if err != nil {
+		return "", err
 	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	return m.GenerateTokenWithExpiry(id, email, name, expiry)
 }
 
 // GenerateRefreshToken generates a refresh token with longer expiration
+//
+// Deprecated: tokens from GenerateRefreshToken cannot be rotated or revoked.
+// Use IssueTokenPair and RotateRefreshToken instead.
 func GenerateRefreshToken(id uuid.UUID, email, name string) (string, error) {
-	if refreshTokenExpiry == 0 {
-		Init()
+	m, err := Default()
+	if err != nil {
+		return "", err
 	}
-	return GenerateTokenWithExpiry(id, email, name, refreshTokenExpiry)
+	return m.GenerateRefreshToken(id, email, name)
 }
 
-// ValidateToken validates a JWT token and returns the claims
+// ValidateToken validates a JWT access token and returns the claims
 func ValidateToken(tokenString string) (*Claims, error) {
-	if len(jwtSecret) == 0 {
-		Init()
-	}
-
-	token, err := jwt.ParseWithClaims(
-		tokenString,
-		&Claims{},
-		func(token *jwt.Token) (interface{}, error) {
-			// Verify the signing method
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, errors.New("unexpected signing method")
-			}
-			// Return the secret key for verification
-			return jwtSecret, nil
-		},
-		jwt.WithValidMethods([]string{"HS256"}),
-		// jwt.WithSkipClaimsValidation(true),
-		jwt.WithLeeway(30*time.Second),
-	)
-
+	m, err := Default()
 	if err != nil {
 		return nil, err
 	}
-
-	// Check if token is valid
-	if !token.Valid {
-		return nil, errors.New("token is not valid")
-	}
-
-	if claims, ok := token.Claims.(*Claims); ok {
-		return claims, nil
-	}
-
-	return nil, errors.New("invalid token claims")
+	return m.ValidateToken(tokenString)
 }
 
 // ComparePassword compares a hashed password with a plain password
@@ -140,6 +133,15 @@ func ComparePassword(hashedPassword, plainPassword string) bool {
 	return err == nil
 }
 
+func parseDuration(key string, defaultValue time.Duration) time.Duration {
+	if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+	}
+	return defaultValue
+}
+
 func parseDurationOrHours(durationKey, hoursKey string, defaultValue time.Duration) time.Duration {
 	if v := strings.TrimSpace(os.Getenv(durationKey)); v != "" {
 		if d, err := time.ParseDuration(v); err == nil && d > 0 {
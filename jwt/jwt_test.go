@@ -14,10 +14,8 @@ func setupJWTTest() {
 	os.Setenv("JWT_ACCESS_TOKEN_EXPIRY_HOURS", "1")
 	os.Setenv("JWT_REFRESH_TOKEN_EXPIRY_DAYS", "7")
 
-	// Reset global variables
-	jwtSecret = nil
-	accessTokenExpiry = 0
-	refreshTokenExpiry = 0
+	// Reset the package-level manager
+	SetDefault(nil)
 
 	Init()
 }
@@ -28,6 +26,11 @@ func teardownJWTTest() {
 	os.Unsetenv("JWT_REFRESH_TOKEN_EXPIRY_DAYS")
 	os.Unsetenv("JWT_ACCESS_TOKEN_EXPIRY")
 	os.Unsetenv("JWT_REFRESH_TOKEN_EXPIRY")
+	os.Unsetenv("JWT_SIGNING_KEY_FILE")
+	os.Unsetenv("JWT_SIGNING_KEY_ID")
+	os.Unsetenv("JWT_VERIFICATION_KEY_FILES")
+	os.Unsetenv("JWT_ISSUER")
+	os.Unsetenv("JWT_AUDIENCE")
 }
 
 func TestInit(t *testing.T) {
@@ -37,8 +40,7 @@ func TestInit(t *testing.T) {
 	os.Setenv("JWT_SECRET", "test-secret")
 	os.Setenv("JWT_ACCESS_TOKEN_EXPIRY_HOURS", "2")
 
-	jwtSecret = nil
-	accessTokenExpiry = 0
+	SetDefault(nil)
 
 	// Should not panic
 	func() {
@@ -50,12 +52,13 @@ func TestInit(t *testing.T) {
 		Init()
 	}()
 
-	if len(jwtSecret) == 0 {
-		t.Error("Init did not set jwtSecret")
+	m, err := Default()
+	if err != nil {
+		t.Fatalf("Default returned error after Init: %v", err)
 	}
 
-	if accessTokenExpiry == 0 {
-		t.Error("Init did not set accessTokenExpiry")
+	if m.AccessTokenExpiry() != 2*time.Hour {
+		t.Errorf("AccessTokenExpiry = %v, expected %v", m.AccessTokenExpiry(), 2*time.Hour)
 	}
 }
 
@@ -64,7 +67,7 @@ func TestInit_PanicsOnMissingSecret(t *testing.T) {
 	defer teardownJWTTest()
 
 	os.Unsetenv("JWT_SECRET")
-	jwtSecret = nil
+	SetDefault(nil)
 
 	defer func() {
 		if r := recover(); r == nil {
@@ -148,7 +151,7 @@ func TestGenerateRefreshToken(t *testing.T) {
 	}
 
 	// Validate the token
-	claims, err := ValidateToken(token)
+	claims, err := ValidateRefreshToken(token)
 	if err != nil {
 		t.Errorf("ValidateToken returned error: %v", err)
 	}
@@ -0,0 +1,345 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrUnsupportedKey = errors.New("unsupported key type")
+	ErrInvalidPEM     = errors.New("invalid PEM data")
+	ErrUnknownKeyID   = errors.New("unknown key id")
+)
+
+// SigningKey is a private key used to sign tokens
+type SigningKey struct {
+	ID     string
+	Method jwt.SigningMethod
+	Key    crypto.Signer
+}
+
+// VerificationKey is a public key used to verify tokens
+type VerificationKey struct {
+	ID     string
+	Method jwt.SigningMethod
+	Key    crypto.PublicKey
+}
+
+// Public returns the verification key matching the signing key
+func (k *SigningKey) Public() *VerificationKey {
+	return &VerificationKey{
+		ID:     k.ID,
+		Method: k.Method,
+		Key:    k.Key.Public(),
+	}
+}
+
+// KeySet holds the active signing key and every public key accepted for verification.
+// Keeping retired public keys in the set lets tokens signed before a rotation stay valid
+// until they expire.
+type KeySet struct {
+	mu      sync.RWMutex
+	signing *SigningKey
+	keys    map[string]*VerificationKey
+}
+
+// NewKeySet creates an empty key set
+func NewKeySet() *KeySet {
+	return &KeySet{keys: make(map[string]*VerificationKey)}
+}
+
+// SetSigningKey sets the key used to sign new tokens and registers its public half
+func (ks *KeySet) SetSigningKey(key *SigningKey) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.signing = key
+	ks.keys[key.ID] = key.Public()
+}
+
+// AddVerificationKey registers a public key accepted for verification
+func (ks *KeySet) AddVerificationKey(key *VerificationKey) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.keys[key.ID] = key
+}
+
+// RemoveVerificationKey removes a public key from the set
+func (ks *KeySet) RemoveVerificationKey(kid string) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	delete(ks.keys, kid)
+}
+
+// SigningKey returns the active signing key, or nil if the set is verify-only
+func (ks *KeySet) SigningKey() *SigningKey {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return ks.signing
+}
+
+// Lookup returns the verification key for a key ID
+func (ks *KeySet) Lookup(kid string) (*VerificationKey, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	key, ok := ks.keys[kid]
+	return key, ok
+}
+
+// Algorithms returns the signing algorithms used by keys in the set
+func (ks *KeySet) Algorithms() []string {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	var algs []string
+	for _, key := range ks.keys {
+		alg := key.Method.Alg()
+		if !seen[alg] {
+			seen[alg] = true
+			algs = append(algs, alg)
+		}
+	}
+	sort.Strings(algs)
+	return algs
+}
+
+// Len returns the number of verification keys in the set
+func (ks *KeySet) Len() int {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return len(ks.keys)
+}
+
+// JWK represents a single public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS represents a JSON Web Key Set document
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys of the set as a JSON Web Key Set, ordered by key ID
+func (ks *KeySet) JWKS() JWKS {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	doc := JWKS{Keys: make([]JWK, 0, len(ks.keys))}
+	for _, key := range ks.keys {
+		jwk, err := toJWK(key)
+		if err != nil {
+			continue
+		}
+		doc.Keys = append(doc.Keys, jwk)
+	}
+	sort.Slice(doc.Keys, func(i, j int) bool { return doc.Keys[i].Kid < doc.Keys[j].Kid })
+	return doc
+}
+
+// LoadSigningKeyFromPEM reads a PEM encoded private key from a file.
+// If kid is empty the RFC 7638 thumbprint of the public key is used.
+func LoadSigningKeyFromPEM(path, kid string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %w", err)
+	}
+	return ParseSigningKeyPEM(data, kid)
+}
+
+// ParseSigningKeyPEM parses a PEM encoded RSA, ECDSA or Ed25519 private key.
+// PKCS#8, PKCS#1 and SEC 1 encodings are accepted.
+func ParseSigningKeyPEM(data []byte, kid string) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrInvalidPEM
+	}
+
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, ErrUnsupportedKey
+	}
+
+	method, err := signingMethodFor(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	if kid == "" {
+		if kid, err = Thumbprint(signer.Public()); err != nil {
+			return nil, err
+		}
+	}
+
+	return &SigningKey{ID: kid, Method: method, Key: signer}, nil
+}
+
+// LoadVerificationKeyFromPEM reads a PEM encoded public key from a file.
+// If kid is empty the RFC 7638 thumbprint of the key is used.
+func LoadVerificationKeyFromPEM(path, kid string) (*VerificationKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read verification key: %w", err)
+	}
+	return ParseVerificationKeyPEM(data, kid)
+}
+
+// ParseVerificationKeyPEM parses a PEM encoded public key or certificate
+func ParseVerificationKeyPEM(data []byte, kid string) (*VerificationKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrInvalidPEM
+	}
+
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+		if cert, err = x509.ParseCertificate(block.Bytes); err == nil {
+			key = cert.PublicKey
+		}
+	default:
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+
+	method, err := signingMethodFor(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if kid == "" {
+		if kid, err = Thumbprint(key); err != nil {
+			return nil, err
+		}
+	}
+
+	return &VerificationKey{ID: kid, Method: method, Key: key}, nil
+}
+
+// Thumbprint computes the RFC 7638 JWK thumbprint of a public key
+func Thumbprint(key crypto.PublicKey) (string, error) {
+	jwk, err := toJWK(&VerificationKey{Key: key, Method: jwt.SigningMethodNone})
+	if err != nil {
+		return "", err
+	}
+
+	// Members must be in lexicographic order with no whitespace
+	var canonical []byte
+	switch jwk.Kty {
+	case "RSA":
+		canonical, err = json.Marshal(struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N})
+	case "EC":
+		canonical, err = json.Marshal(struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{jwk.Crv, jwk.Kty, jwk.X, jwk.Y})
+	case "OKP":
+		canonical, err = json.Marshal(struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X})
+	}
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(canonical)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// signingMethodFor picks the JWS algorithm for a public key
+func signingMethodFor(key crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return jwt.SigningMethodES256, nil
+		case elliptic.P384():
+			return jwt.SigningMethodES384, nil
+		case elliptic.P521():
+			return jwt.SigningMethodES512, nil
+		}
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	}
+	return nil, ErrUnsupportedKey
+}
+
+// toJWK converts a verification key to its JWK representation
+func toJWK(key *VerificationKey) (JWK, error) {
+	jwk := JWK{Kid: key.ID, Use: "sig", Alg: key.Method.Alg()}
+
+	switch k := key.Key.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(k.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = k.Curve.Params().Name
+		jwk.X = base64.RawURLEncoding.EncodeToString(k.X.FillBytes(make([]byte, size)))
+		jwk.Y = base64.RawURLEncoding.EncodeToString(k.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(k)
+	default:
+		return JWK{}, ErrUnsupportedKey
+	}
+
+	return jwk, nil
+}
@@ -0,0 +1,244 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func writePrivateKeyPEM(t *testing.T, key crypto.Signer) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey returned error: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "private.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+	return path
+}
+
+func writePublicKeyPEM(t *testing.T, key crypto.PublicKey) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatalf("MarshalPKIXPublicKey returned error: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "public.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+	return path
+}
+
+func testSigners(t *testing.T) map[string]crypto.Signer {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey returned error: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("ecdsa.GenerateKey returned error: %v", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("ed25519.GenerateKey returned error: %v", err)
+	}
+	return map[string]crypto.Signer{
+		"RS256": rsaKey,
+		"ES256": ecKey,
+		"EdDSA": edKey,
+	}
+}
+
+func TestAsymmetricSigning(t *testing.T) {
+	for alg, signer := range testSigners(t) {
+		t.Run(alg, func(t *testing.T) {
+			teardownJWTTest()
+			defer teardownJWTTest()
+
+			os.Setenv("JWT_SIGNING_KEY_FILE", writePrivateKeyPEM(t, signer))
+			os.Setenv("JWT_SIGNING_KEY_ID", "key-1")
+			SetDefault(nil)
+			Init()
+
+			id := uuid.New()
+			tokenString, err := GenerateToken(id, "test@example.com", "Test User")
+			if err != nil {
+				t.Fatalf("GenerateToken returned error: %v", err)
+			}
+
+			token, _, err := jwt.NewParser().ParseUnverified(tokenString, &Claims{})
+			if err != nil {
+				t.Fatalf("ParseUnverified returned error: %v", err)
+			}
+			if token.Method.Alg() != alg {
+				t.Errorf("alg = %s, expected %s", token.Method.Alg(), alg)
+			}
+			if token.Header["kid"] != "key-1" {
+				t.Errorf("kid = %v, expected key-1", token.Header["kid"])
+			}
+
+			claims, err := ValidateToken(tokenString)
+			if err != nil {
+				t.Fatalf("ValidateToken returned error: %v", err)
+			}
+			if claims.UUID != id.String() {
+				t.Errorf("Claims UUID = %s, expected %s", claims.UUID, id.String())
+			}
+		})
+	}
+}
+
+func TestValidateToken_VerifyOnly(t *testing.T) {
+	teardownJWTTest()
+	defer teardownJWTTest()
+
+	signer := testSigners(t)["ES256"]
+	signing, err := ParseSigningKeyPEM(readFile(t, writePrivateKeyPEM(t, signer)), "issuer-key")
+	if err != nil {
+		t.Fatalf("ParseSigningKeyPEM returned error: %v", err)
+	}
+
+	token := jwt.NewWithClaims(signing.Method, Claims{UUID: "abc"})
+	token.Header["kid"] = signing.ID
+	tokenString, err := token.SignedString(signing.Key)
+	if err != nil {
+		t.Fatalf("SignedString returned error: %v", err)
+	}
+
+	// A downstream service only holds the public key
+	os.Setenv("JWT_VERIFICATION_KEY_FILES", "issuer-key="+writePublicKeyPEM(t, signer.Public()))
+	SetDefault(nil)
+	Init()
+
+	claims, err := ValidateToken(tokenString)
+	if err != nil {
+		t.Fatalf("ValidateToken returned error: %v", err)
+	}
+	if claims.UUID != "abc" {
+		t.Errorf("Claims UUID = %s, expected abc", claims.UUID)
+	}
+
+	if _, err := GenerateToken(uuid.New(), "test@example.com", "Test User"); err == nil {
+		t.Error("GenerateToken should fail without a signing key")
+	}
+}
+
+func TestValidateToken_UnknownKeyID(t *testing.T) {
+	teardownJWTTest()
+	defer teardownJWTTest()
+
+	signers := testSigners(t)
+	os.Setenv("JWT_SIGNING_KEY_FILE", writePrivateKeyPEM(t, signers["RS256"]))
+	SetDefault(nil)
+	Init()
+
+	// Signed by a key that is not in the set
+	other, err := ParseSigningKeyPEM(readFile(t, writePrivateKeyPEM(t, signers["EdDSA"])), "")
+	if err != nil {
+		t.Fatalf("ParseSigningKeyPEM returned error: %v", err)
+	}
+	token := jwt.NewWithClaims(other.Method, Claims{UUID: "abc"})
+	token.Header["kid"] = other.ID
+	tokenString, _ := token.SignedString(other.Key)
+
+	if _, err := ValidateToken(tokenString); err == nil {
+		t.Error("ValidateToken should reject a token signed by an unknown key")
+	}
+}
+
+func TestValidateToken_AlgorithmMismatch(t *testing.T) {
+	teardownJWTTest()
+	defer teardownJWTTest()
+
+	os.Setenv("JWT_SECRET", "test-secret-key-for-unit-testing")
+	os.Setenv("JWT_SIGNING_KEY_FILE", writePrivateKeyPEM(t, testSigners(t)["RS256"]))
+	SetDefault(nil)
+	Init()
+
+	// HS256 token claiming the RSA kid must not be accepted
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{UUID: "abc"})
+	token.Header["kid"] = GetKeySet().SigningKey().ID
+	tokenString, _ := token.SignedString([]byte("test-secret-key-for-unit-testing"))
+
+	if _, err := ValidateToken(tokenString); err == nil {
+		t.Error("ValidateToken should reject a token whose alg does not match its key")
+	}
+}
+
+func TestThumbprint(t *testing.T) {
+	signer := testSigners(t)["ES256"]
+
+	a, err := Thumbprint(signer.Public())
+	if err != nil {
+		t.Fatalf("Thumbprint returned error: %v", err)
+	}
+	b, _ := Thumbprint(signer.Public())
+	if a == "" || a != b {
+		t.Errorf("Thumbprint should be stable, got %q and %q", a, b)
+	}
+}
+
+func TestJWKSHandler(t *testing.T) {
+	teardownJWTTest()
+	defer teardownJWTTest()
+
+	gin.SetMode(gin.TestMode)
+	signers := testSigners(t)
+	os.Setenv("JWT_SIGNING_KEY_FILE", writePrivateKeyPEM(t, signers["RS256"]))
+	os.Setenv("JWT_SIGNING_KEY_ID", "current")
+	os.Setenv("JWT_VERIFICATION_KEY_FILES", "previous="+writePublicKeyPEM(t, signers["EdDSA"].Public()))
+	SetDefault(nil)
+	Init()
+
+	router := gin.New()
+	router.GET("/.well-known/jwks.json", JWKSHandler())
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, expected %d", w.Code, http.StatusOK)
+	}
+
+	var doc JWKS
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("failed to decode JWKS: %v", err)
+	}
+	if len(doc.Keys) != 2 {
+		t.Fatalf("JWKS has %d keys, expected 2", len(doc.Keys))
+	}
+	if doc.Keys[0].Kid != "current" || doc.Keys[0].Kty != "RSA" || doc.Keys[0].N == "" {
+		t.Errorf("unexpected RSA key: %+v", doc.Keys[0])
+	}
+	if doc.Keys[1].Kid != "previous" || doc.Keys[1].Kty != "OKP" || doc.Keys[1].Alg != "EdDSA" {
+		t.Errorf("unexpected Ed25519 key: %+v", doc.Keys[1])
+	}
+}
+
+func readFile(t *testing.T, path string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+	return data
+}
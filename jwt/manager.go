@@ -0,0 +1,323 @@
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	goredis "github.com/redis/go-redis/v9"
+
+	"github.com/writdev-alt/portal-api-shared/redis"
+)
+
+const (
+	defaultAccessTokenExpiry  = time.Hour
+	defaultRefreshTokenExpiry = 7 * 24 * time.Hour
+	defaultServiceTokenExpiry = 15 * time.Minute
+	defaultLeeway             = 30 * time.Second
+)
+
+var ErrNoKeys = errors.New("JWT_SECRET or JWT signing keys are not configured")
+
+// Options configures a Manager
+type Options struct {
+	// Secret enables HS256. Optional when Keys holds at least one key.
+	Secret []byte
+	// Keys holds asymmetric signing and verification keys
+	Keys *KeySet
+	// Issuer is stamped into iss and, when set, required on validation
+	Issuer string
+	// Audience is stamped into aud and, when set, validated tokens must name one of them
+	Audience []string
+	// AccessTokenExpiry defaults to 1 hour
+	AccessTokenExpiry time.Duration
+	// RefreshTokenExpiry defaults to 7 days
+	RefreshTokenExpiry time.Duration
+	// ServiceTokenExpiry is the lifetime of client-credential tokens. Defaults to 15 minutes.
+	ServiceTokenExpiry time.Duration
+	// Leeway is the clock skew tolerated when checking exp, nbf and iat. Defaults to 30 seconds.
+	Leeway time.Duration
+	// Clock returns the current time. Defaults to time.Now.
+	Clock func() time.Time
+	// Redis holds refresh token families, revocations and action nonces.
+	// Defaults to the shared client of the redis package.
+	Redis *goredis.Client
+}
+
+// Manager issues and validates tokens for one configuration. Managers hold no
+// global state, so a process can run one per service or per tenant.
+type Manager struct {
+	secret             []byte
+	keys               *KeySet
+	issuer             string
+	audience           []string
+	accessTokenExpiry  time.Duration
+	refreshTokenExpiry time.Duration
+	serviceTokenExpiry time.Duration
+	leeway             time.Duration
+	clock              func() time.Time
+	redis              *goredis.Client
+}
+
+// NewManager creates a manager from options
+func NewManager(opts Options) (*Manager, error) {
+	keys := opts.Keys
+	if keys == nil {
+		keys = NewKeySet()
+	}
+	if len(opts.Secret) == 0 && keys.Len() == 0 {
+		return nil, ErrNoKeys
+	}
+
+	m := &Manager{
+		secret:             append([]byte(nil), opts.Secret...),
+		keys:               keys,
+		issuer:             opts.Issuer,
+		audience:           append([]string(nil), opts.Audience...),
+		accessTokenExpiry:  opts.AccessTokenExpiry,
+		refreshTokenExpiry: opts.RefreshTokenExpiry,
+		serviceTokenExpiry: opts.ServiceTokenExpiry,
+		leeway:             opts.Leeway,
+		clock:              opts.Clock,
+		redis:              opts.Redis,
+	}
+	if m.accessTokenExpiry <= 0 {
+		m.accessTokenExpiry = defaultAccessTokenExpiry
+	}
+	if m.refreshTokenExpiry <= 0 {
+		m.refreshTokenExpiry = defaultRefreshTokenExpiry
+	}
+	if m.serviceTokenExpiry <= 0 {
+		m.serviceTokenExpiry = defaultServiceTokenExpiry
+	}
+	if m.leeway <= 0 {
+		m.leeway = defaultLeeway
+	}
+	if m.clock == nil {
+		m.clock = time.Now
+	}
+
+	return m, nil
+}
+
+// OptionsFromEnv loads options from environment variables.
+//
+// Required (at least one):
+// - JWT_SECRET (HS256)
+// - JWT_SIGNING_KEY_FILE (PEM private key; RSA, ECDSA or Ed25519) or JWT_VERIFICATION_KEY_FILES
+//
+// Optional:
+// - JWT_SIGNING_KEY_ID (kid header, defaults to the RFC 7638 key thumbprint)
+// - JWT_VERIFICATION_KEY_FILES (comma separated PEM public keys, "kid=path" or "path")
+// - JWT_ISSUER, JWT_AUDIENCE (comma separated)
+// - JWT_ACCESS_TOKEN_EXPIRY (Go duration string, e.g. "1h", "30m") OR JWT_ACCESS_TOKEN_EXPIRY_HOURS (int)
+// - JWT_REFRESH_TOKEN_EXPIRY (Go duration string, e.g. "168h") OR JWT_REFRESH_TOKEN_EXPIRY_DAYS (int)
+// - JWT_SERVICE_TOKEN_EXPIRY (Go duration string, e.g. "15m")
+//
+// When a signing key is configured new tokens are signed with it, while HS256 tokens are
+// still accepted as long as JWT_SECRET is set. Services that only verify tokens can be
+// configured with JWT_VERIFICATION_KEY_FILES alone.
+func OptionsFromEnv() (Options, error) {
+	keys, err := loadKeySetFromEnv()
+	if err != nil {
+		return Options{}, fmt.Errorf("failed to load JWT keys: %w", err)
+	}
+
+	opts := Options{
+		Keys:               keys,
+		Issuer:             strings.TrimSpace(os.Getenv("JWT_ISSUER")),
+		AccessTokenExpiry:  parseDurationOrHours("JWT_ACCESS_TOKEN_EXPIRY", "JWT_ACCESS_TOKEN_EXPIRY_HOURS", defaultAccessTokenExpiry),
+		RefreshTokenExpiry: parseDurationOrDays("JWT_REFRESH_TOKEN_EXPIRY", "JWT_REFRESH_TOKEN_EXPIRY_DAYS", defaultRefreshTokenExpiry),
+		ServiceTokenExpiry: parseDuration("JWT_SERVICE_TOKEN_EXPIRY", defaultServiceTokenExpiry),
+	}
+	if secret := os.Getenv("JWT_SECRET"); strings.TrimSpace(secret) != "" {
+		opts.Secret = []byte(secret)
+	}
+	for _, aud := range strings.Split(os.Getenv("JWT_AUDIENCE"), ",") {
+		if aud = strings.TrimSpace(aud); aud != "" {
+			opts.Audience = append(opts.Audience, aud)
+		}
+	}
+
+	return opts, nil
+}
+
+// NewManagerFromEnv creates a manager configured by OptionsFromEnv
+func NewManagerFromEnv() (*Manager, error) {
+	opts, err := OptionsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return NewManager(opts)
+}
+
+// KeySet returns the manager's key set
+func (m *Manager) KeySet() *KeySet {
+	return m.keys
+}
+
+// AccessTokenExpiry returns the access token lifetime
+func (m *Manager) AccessTokenExpiry() time.Duration {
+	return m.accessTokenExpiry
+}
+
+// RefreshTokenExpiry returns the refresh token lifetime
+func (m *Manager) RefreshTokenExpiry() time.Duration {
+	return m.refreshTokenExpiry
+}
+
+// ServiceTokenExpiry returns the client-credential token lifetime
+func (m *Manager) ServiceTokenExpiry() time.Duration {
+	return m.serviceTokenExpiry
+}
+
+// ForAudience returns a copy of the manager that stamps and requires a different
+// audience, e.g. to issue admin portal and merchant API tokens from one service
+// with the same keys.
+func (m *Manager) ForAudience(audience ...string) *Manager {
+	clone := *m
+	clone.audience = append([]string(nil), audience...)
+	return &clone
+}
+
+// WithRedis returns a copy of the manager that keeps its token state in client
+// instead of the shared Redis client, so tenants can be isolated from each other.
+func (m *Manager) WithRedis(client *goredis.Client) *Manager {
+	clone := *m
+	clone.redis = client
+	return &clone
+}
+
+// GenerateToken generates an access token
+func (m *Manager) GenerateToken(id uuid.UUID, email, name string) (string, error) {
+	return m.GenerateTokenWithExpiry(id, email, name, m.accessTokenExpiry)
+}
+
+// GenerateTokenWithExpiry generates an access token with custom expiration time
+func (m *Manager) GenerateTokenWithExpiry(id uuid.UUID, email, name string, expiry time.Duration) (string, error) {
+	return m.sign(m.newClaims(TokenTypeAccess, Identity{UUID: id, Email: email, Name: name}, expiry))
+}
+
+// GenerateRefreshToken generates a refresh token with longer expiration
+//
+// Deprecated: tokens from GenerateRefreshToken cannot be rotated or revoked.
+// Use IssueTokenPair and RotateRefreshToken instead.
+func (m *Manager) GenerateRefreshToken(id uuid.UUID, email, name string) (string, error) {
+	return m.sign(m.newClaims(TokenTypeRefresh, Identity{UUID: id, Email: email, Name: name}, m.refreshTokenExpiry))
+}
+
+// ValidateToken validates an access token and returns the claims.
+// Tokens of any other type, including refresh tokens, are rejected.
+func (m *Manager) ValidateToken(tokenString string) (*Claims, error) {
+	return m.ValidateAccessToken(tokenString)
+}
+
+// now returns the current time from the manager's clock
+func (m *Manager) now() time.Time {
+	return m.clock()
+}
+
+// tokenStore returns the store for the manager's Redis client, falling back to
+// the shared client. It returns nil when neither is configured.
+func (m *Manager) tokenStore() *redis.TokenStore {
+	if m.redis != nil {
+		return redis.NewTokenStore(m.redis)
+	}
+	if redis.IsEnabled() {
+		return redis.NewTokenStore(redis.GetRedis())
+	}
+	return nil
+}
+
+// sign signs claims with the configured signing key, falling back to HS256
+func (m *Manager) sign(claims jwt.Claims) (string, error) {
+	if key := m.keys.SigningKey(); key != nil {
+		token := jwt.NewWithClaims(key.Method, claims)
+		token.Header["kid"] = key.ID
+		return token.SignedString(key.Key)
+	}
+
+	if len(m.secret) == 0 {
+		return "", errors.New("no signing key configured")
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(m.secret)
+}
+
+// keyFunc resolves the verification key for a token from its kid header
+func (m *Manager) keyFunc(token *jwt.Token) (interface{}, error) {
+	if kid, ok := token.Header["kid"].(string); ok && kid != "" {
+		key, found := m.keys.Lookup(kid)
+		if !found {
+			return nil, ErrUnknownKeyID
+		}
+		// A key is only ever valid for the algorithm it was registered with
+		if key.Method.Alg() != token.Method.Alg() {
+			return nil, errors.New("unexpected signing method")
+		}
+		return key.Key, nil
+	}
+
+	// Tokens without a kid are legacy HS256 tokens
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok || len(m.secret) == 0 {
+		return nil, errors.New("unexpected signing method")
+	}
+	return m.secret, nil
+}
+
+// parserOptions returns the options applied when parsing tokens
+func (m *Manager) parserOptions() []jwt.ParserOption {
+	var methods []string
+	if len(m.secret) > 0 {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	methods = append(methods, m.keys.Algorithms()...)
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+		jwt.WithLeeway(m.leeway),
+		jwt.WithTimeFunc(m.clock),
+	}
+	if m.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(m.issuer))
+	}
+	if len(m.audience) > 0 {
+		opts = append(opts, jwt.WithAudience(m.audience...))
+	}
+	return opts
+}
+
+// loadKeySetFromEnv loads the signing and verification keys configured in the environment
+func loadKeySetFromEnv() (*KeySet, error) {
+	keys := NewKeySet()
+
+	if path := strings.TrimSpace(os.Getenv("JWT_SIGNING_KEY_FILE")); path != "" {
+		key, err := LoadSigningKeyFromPEM(path, strings.TrimSpace(os.Getenv("JWT_SIGNING_KEY_ID")))
+		if err != nil {
+			return nil, err
+		}
+		keys.SetSigningKey(key)
+	}
+
+	for _, entry := range strings.Split(os.Getenv("JWT_VERIFICATION_KEY_FILES"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		kid, path := "", entry
+		if i := strings.Index(entry, "="); i >= 0 {
+			kid, path = strings.TrimSpace(entry[:i]), strings.TrimSpace(entry[i+1:])
+		}
+		key, err := LoadVerificationKeyFromPEM(path, kid)
+		if err != nil {
+			return nil, err
+		}
+		keys.AddVerificationKey(key)
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,175 @@
+package jwt
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func newTestManager(t *testing.T, opts Options) *Manager {
+	t.Helper()
+	if opts.Secret == nil && opts.Keys == nil {
+		opts.Secret = []byte("test-secret-key-for-unit-testing")
+	}
+	m, err := NewManager(opts)
+	if err != nil {
+		t.Fatalf("NewManager returned error: %v", err)
+	}
+	return m
+}
+
+func TestNewManager_NoKeys(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewManager(Options{})
+	if !errors.Is(err, ErrNoKeys) {
+		t.Errorf("NewManager error = %v, expected %v", err, ErrNoKeys)
+	}
+}
+
+func TestNewManager_Defaults(t *testing.T) {
+	t.Parallel()
+
+	m := newTestManager(t, Options{})
+	if m.AccessTokenExpiry() != time.Hour {
+		t.Errorf("AccessTokenExpiry = %v, expected %v", m.AccessTokenExpiry(), time.Hour)
+	}
+	if m.RefreshTokenExpiry() != 7*24*time.Hour {
+		t.Errorf("RefreshTokenExpiry = %v, expected %v", m.RefreshTokenExpiry(), 7*24*time.Hour)
+	}
+}
+
+func TestManager_IsolatedSecrets(t *testing.T) {
+	t.Parallel()
+
+	a := newTestManager(t, Options{Secret: []byte("tenant-a-secret")})
+	b := newTestManager(t, Options{Secret: []byte("tenant-b-secret")})
+
+	token, err := a.GenerateToken(uuid.New(), "test@example.com", "Test User")
+	if err != nil {
+		t.Fatalf("GenerateToken returned error: %v", err)
+	}
+	if _, err := a.ValidateToken(token); err != nil {
+		t.Errorf("ValidateToken returned error for own token: %v", err)
+	}
+	if _, err := b.ValidateToken(token); err == nil {
+		t.Error("ValidateToken should reject a token signed by another manager")
+	}
+}
+
+func TestManager_WithRedis(t *testing.T) {
+	t.Parallel()
+
+	a := newTestManager(t, Options{Redis: newTestRedis(t)})
+	b := a.WithRedis(newTestRedis(t))
+
+	id := uuid.New()
+	token, err := a.GenerateToken(id, "test@example.com", "Test User")
+	if err != nil {
+		t.Fatalf("GenerateToken returned error: %v", err)
+	}
+	if err := a.RevokeUserTokens(id.String()); err != nil {
+		t.Fatalf("RevokeUserTokens returned error: %v", err)
+	}
+
+	if _, err := a.ValidateToken(token); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("ValidateToken error = %v, expected %v", err, ErrTokenRevoked)
+	}
+	if _, err := b.ValidateToken(token); err != nil {
+		t.Errorf("ValidateToken returned error for a manager with its own Redis: %v", err)
+	}
+}
+
+func TestManager_Clock(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	m := newTestManager(t, Options{Clock: clock, AccessTokenExpiry: 10 * time.Minute})
+
+	token, err := m.GenerateToken(uuid.New(), "test@example.com", "Test User")
+	if err != nil {
+		t.Fatalf("GenerateToken returned error: %v", err)
+	}
+
+	claims, err := m.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("ValidateToken returned error: %v", err)
+	}
+	if !claims.IssuedAt.Time.Equal(now) {
+		t.Errorf("IssuedAt = %v, expected %v", claims.IssuedAt.Time, now)
+	}
+
+	now = now.Add(11 * time.Minute)
+	if _, err := m.ValidateToken(token); !errors.Is(err, jwt.ErrTokenExpired) {
+		t.Errorf("ValidateToken error = %v, expected %v", err, jwt.ErrTokenExpired)
+	}
+}
+
+func TestManager_IssuerAndAudience(t *testing.T) {
+	t.Parallel()
+
+	secret := []byte("shared-secret")
+	admin := newTestManager(t, Options{Secret: secret, Issuer: "portal", Audience: []string{"admin"}})
+	merchant := newTestManager(t, Options{Secret: secret, Issuer: "portal", Audience: []string{"merchant"}})
+	other := newTestManager(t, Options{Secret: secret, Issuer: "other", Audience: []string{"admin"}})
+
+	token, err := admin.GenerateToken(uuid.New(), "test@example.com", "Test User")
+	if err != nil {
+		t.Fatalf("GenerateToken returned error: %v", err)
+	}
+
+	if _, err := admin.ValidateToken(token); err != nil {
+		t.Errorf("ValidateToken returned error: %v", err)
+	}
+	if _, err := merchant.ValidateToken(token); !errors.Is(err, jwt.ErrTokenInvalidAudience) {
+		t.Errorf("ValidateToken error = %v, expected %v", err, jwt.ErrTokenInvalidAudience)
+	}
+	if _, err := other.ValidateToken(token); !errors.Is(err, jwt.ErrTokenInvalidIssuer) {
+		t.Errorf("ValidateToken error = %v, expected %v", err, jwt.ErrTokenInvalidIssuer)
+	}
+}
+
+func TestDefault_ReturnsErrorInsteadOfPanic(t *testing.T) {
+	teardownJWTTest()
+	defer teardownJWTTest()
+	SetDefault(nil)
+
+	if _, err := GenerateToken(uuid.New(), "test@example.com", "Test User"); !errors.Is(err, ErrNoKeys) {
+		t.Errorf("GenerateToken error = %v, expected %v", err, ErrNoKeys)
+	}
+	if _, err := ValidateToken("invalid.token.string"); !errors.Is(err, ErrNoKeys) {
+		t.Errorf("ValidateToken error = %v, expected %v", err, ErrNoKeys)
+	}
+}
+
+func TestOptionsFromEnv(t *testing.T) {
+	teardownJWTTest()
+	defer teardownJWTTest()
+
+	os.Setenv("JWT_SECRET", "env-secret")
+	os.Setenv("JWT_ISSUER", "portal")
+	os.Setenv("JWT_AUDIENCE", "admin, merchant")
+	os.Setenv("JWT_ACCESS_TOKEN_EXPIRY", "15m")
+
+	opts, err := OptionsFromEnv()
+	if err != nil {
+		t.Fatalf("OptionsFromEnv returned error: %v", err)
+	}
+	if string(opts.Secret) != "env-secret" {
+		t.Errorf("Secret = %s, expected env-secret", opts.Secret)
+	}
+	if opts.Issuer != "portal" {
+		t.Errorf("Issuer = %s, expected portal", opts.Issuer)
+	}
+	if len(opts.Audience) != 2 || opts.Audience[1] != "merchant" {
+		t.Errorf("Audience = %v, expected [admin merchant]", opts.Audience)
+	}
+	if opts.AccessTokenExpiry != 15*time.Minute {
+		t.Errorf("AccessTokenExpiry = %v, expected %v", opts.AccessTokenExpiry, 15*time.Minute)
+	}
+}
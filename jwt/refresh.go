@@ -0,0 +1,163 @@
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/writdev-alt/portal-api-shared/redis"
+)
+
+var (
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+	ErrSessionExpired     = errors.New("session expired")
+	ErrNotRefreshToken    = errors.New("token is not a rotatable refresh token")
+	ErrRedisNotEnabled    = errors.New("redis is not initialized")
+)
+
+// TokenPair is an access token together with the refresh token that renews it
+type TokenPair struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+	ExpiresIn    int64  `json:"expiresIn"` // Access token lifetime in seconds
+}
+
+// IssueTokenPair issues an access token and a refresh token that starts a new
+// rotation family. Each refresh token can be used exactly once; see RotateRefreshToken.
+func (m *Manager) IssueTokenPair(id uuid.UUID, email, name string) (*TokenPair, error) {
+	return m.IssueTokenPairFor(Identity{UUID: id, Email: email, Name: name})
+}
+
+// IssueTokenPairFor issues a token pair for an identity. Roles, permissions and the
+// merchant are carried over on every rotation, and the session ID is set to the
+// rotation family so all tokens of one login share it.
+func (m *Manager) IssueTokenPairFor(identity Identity) (*TokenPair, error) {
+	store := m.tokenStore()
+	if store == nil {
+		return nil, ErrRedisNotEnabled
+	}
+
+	familyID := uuid.NewString()
+	tokenID := uuid.NewString()
+	if err := store.CreateTokenFamily(familyID, tokenID, identity.UUID.String(), m.refreshTokenExpiry); err != nil {
+		return nil, fmt.Errorf("failed to create token family: %w", err)
+	}
+
+	identity.SessionID = familyID
+	return m.issuePair(identity, familyID, tokenID)
+}
+
+// RotateRefreshToken exchanges a refresh token for a new token pair.
+//
+// If the presented token has already been rotated, it is assumed to be stolen:
+// the whole family is revoked and ErrRefreshTokenReused is returned, so the
+// legitimate holder is forced to log in again as well.
+func (m *Manager) RotateRefreshToken(refreshToken string) (*TokenPair, error) {
+	claims, err := m.ValidateRefreshToken(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	if claims.FamilyID == "" || claims.ID == "" {
+		return nil, ErrNotRefreshToken
+	}
+	store := m.tokenStore()
+	if store == nil {
+		return nil, ErrRedisNotEnabled
+	}
+
+	identity, err := claims.identity()
+	if err != nil {
+		return nil, fmt.Errorf("invalid subject: %w", err)
+	}
+
+	nextID := uuid.NewString()
+	result, err := store.RotateTokenFamily(claims.FamilyID, claims.ID, nextID, m.refreshTokenExpiry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate token family: %w", err)
+	}
+
+	switch result {
+	case redis.TokenReuseDetected:
+		return nil, ErrRefreshTokenReused
+	case redis.TokenFamilyNotFound:
+		return nil, ErrSessionExpired
+	}
+
+	return m.issuePair(identity, claims.FamilyID, nextID)
+}
+
+// RevokeRefreshToken ends the session a refresh token belongs to
+func (m *Manager) RevokeRefreshToken(refreshToken string) error {
+	claims, err := m.ValidateRefreshToken(refreshToken)
+	if err != nil {
+		return err
+	}
+	if claims.FamilyID == "" {
+		return ErrNotRefreshToken
+	}
+	store := m.tokenStore()
+	if store == nil {
+		return ErrRedisNotEnabled
+	}
+	return store.RevokeTokenFamily(claims.FamilyID)
+}
+
+// issuePair signs an access token and a refresh token carrying the given family and token ID
+func (m *Manager) issuePair(identity Identity, familyID, tokenID string) (*TokenPair, error) {
+	access, err := m.IssueAccessToken(identity)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := m.newClaims(TokenTypeRefresh, identity, m.refreshTokenExpiry)
+	claims.FamilyID = familyID
+	claims.ID = tokenID
+	refresh, err := m.sign(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		ExpiresIn:    int64(m.accessTokenExpiry / time.Second),
+	}, nil
+}
+
+// IssueTokenPair issues a token pair with the package-level manager
+func IssueTokenPair(id uuid.UUID, email, name string) (*TokenPair, error) {
+	m, err := Default()
+	if err != nil {
+		return nil, err
+	}
+	return m.IssueTokenPair(id, email, name)
+}
+
+// IssueTokenPairFor issues a token pair for an identity with the package-level manager
+func IssueTokenPairFor(identity Identity) (*TokenPair, error) {
+	m, err := Default()
+	if err != nil {
+		return nil, err
+	}
+	return m.IssueTokenPairFor(identity)
+}
+
+// RotateRefreshToken rotates a refresh token with the package-level manager
+func RotateRefreshToken(refreshToken string) (*TokenPair, error) {
+	m, err := Default()
+	if err != nil {
+		return nil, err
+	}
+	return m.RotateRefreshToken(refreshToken)
+}
+
+// RevokeRefreshToken revokes a refresh token family with the package-level manager
+func RevokeRefreshToken(refreshToken string) error {
+	m, err := Default()
+	if err != nil {
+		return err
+	}
+	return m.RevokeRefreshToken(refreshToken)
+}
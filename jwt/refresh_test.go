@@ -0,0 +1,140 @@
+package jwt
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	goredis "github.com/redis/go-redis/v9"
+
+	"github.com/writdev-alt/portal-api-shared/redis"
+	response "github.com/writdev-alt/portal-api-shared/responses"
+)
+
+func setupRedisTest(t *testing.T) *miniredis.Miniredis {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	redis.SetClient(goredis.NewClient(&goredis.Options{Addr: mr.Addr()}))
+	t.Cleanup(func() { redis.SetClient(nil) })
+	return mr
+}
+
+// newTestRedis returns a client for a fresh miniredis instance, to be handed to a
+// single manager instead of the shared client
+func newTestRedis(t *testing.T) *goredis.Client {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestIssueTokenPair_RequiresRedis(t *testing.T) {
+	setupJWTTest()
+	defer teardownJWTTest()
+
+	_, err := IssueTokenPair(uuid.New(), "test@example.com", "Test User")
+	if !errors.Is(err, ErrRedisNotEnabled) {
+		t.Errorf("IssueTokenPair error = %v, expected %v", err, ErrRedisNotEnabled)
+	}
+}
+
+func TestRotateRefreshToken(t *testing.T) {
+	setupJWTTest()
+	defer teardownJWTTest()
+	setupRedisTest(t)
+
+	id := uuid.New()
+	pair, err := IssueTokenPair(id, "test@example.com", "Test User")
+	if err != nil {
+		t.Fatalf("IssueTokenPair returned error: %v", err)
+	}
+	if pair.AccessToken == "" || pair.RefreshToken == "" {
+		t.Fatal("IssueTokenPair returned empty tokens")
+	}
+
+	rotated, err := RotateRefreshToken(pair.RefreshToken)
+	if err != nil {
+		t.Fatalf("RotateRefreshToken returned error: %v", err)
+	}
+	if rotated.RefreshToken == pair.RefreshToken {
+		t.Error("RotateRefreshToken should issue a new refresh token")
+	}
+
+	claims, err := ValidateToken(rotated.AccessToken)
+	if err != nil {
+		t.Fatalf("ValidateToken returned error: %v", err)
+	}
+	if claims.UUID != id.String() {
+		t.Errorf("Claims UUID = %s, expected %s", claims.UUID, id.String())
+	}
+
+	// The new refresh token keeps working
+	if _, err := RotateRefreshToken(rotated.RefreshToken); err != nil {
+		t.Errorf("RotateRefreshToken returned error for current token: %v", err)
+	}
+}
+
+func TestRotateRefreshToken_ReuseRevokesFamily(t *testing.T) {
+	setupJWTTest()
+	defer teardownJWTTest()
+	setupRedisTest(t)
+
+	pair, err := IssueTokenPair(uuid.New(), "test@example.com", "Test User")
+	if err != nil {
+		t.Fatalf("IssueTokenPair returned error: %v", err)
+	}
+	rotated, err := RotateRefreshToken(pair.RefreshToken)
+	if err != nil {
+		t.Fatalf("RotateRefreshToken returned error: %v", err)
+	}
+
+	// Replaying the first token is detected
+	_, err = RotateRefreshToken(pair.RefreshToken)
+	if !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("RotateRefreshToken error = %v, expected %v", err, ErrRefreshTokenReused)
+	}
+	if CaseCode(err) != response.CaseCodeSessionExpired {
+		t.Errorf("CaseCode = %s, expected %s", CaseCode(err), response.CaseCodeSessionExpired)
+	}
+
+	// and the legitimate successor is revoked with it
+	_, err = RotateRefreshToken(rotated.RefreshToken)
+	if !errors.Is(err, ErrSessionExpired) {
+		t.Errorf("RotateRefreshToken error = %v, expected %v", err, ErrSessionExpired)
+	}
+}
+
+func TestRevokeRefreshToken(t *testing.T) {
+	setupJWTTest()
+	defer teardownJWTTest()
+	setupRedisTest(t)
+
+	pair, err := IssueTokenPair(uuid.New(), "test@example.com", "Test User")
+	if err != nil {
+		t.Fatalf("IssueTokenPair returned error: %v", err)
+	}
+	if err := RevokeRefreshToken(pair.RefreshToken); err != nil {
+		t.Fatalf("RevokeRefreshToken returned error: %v", err)
+	}
+
+	if _, err := RotateRefreshToken(pair.RefreshToken); !errors.Is(err, ErrSessionExpired) {
+		t.Errorf("RotateRefreshToken error = %v, expected %v", err, ErrSessionExpired)
+	}
+}
+
+func TestRotateRefreshToken_LegacyToken(t *testing.T) {
+	setupJWTTest()
+	defer teardownJWTTest()
+	setupRedisTest(t)
+
+	token, err := GenerateRefreshToken(uuid.New(), "test@example.com", "Test User")
+	if err != nil {
+		t.Fatalf("GenerateRefreshToken returned error: %v", err)
+	}
+
+	if _, err := RotateRefreshToken(token); !errors.Is(err, ErrNotRefreshToken) {
+		t.Errorf("RotateRefreshToken error = %v, expected %v", err, ErrNotRefreshToken)
+	}
+}
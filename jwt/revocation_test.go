@@ -0,0 +1,130 @@
+package jwt
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestGenerateToken_UniqueID(t *testing.T) {
+	setupJWTTest()
+	defer teardownJWTTest()
+
+	id := uuid.New()
+	first, _ := GenerateToken(id, "test@example.com", "Test User")
+	second, _ := GenerateToken(id, "test@example.com", "Test User")
+
+	a, err := ValidateToken(first)
+	if err != nil {
+		t.Fatalf("ValidateToken returned error: %v", err)
+	}
+	b, err := ValidateToken(second)
+	if err != nil {
+		t.Fatalf("ValidateToken returned error: %v", err)
+	}
+	if a.ID == "" || a.ID == b.ID {
+		t.Errorf("tokens should have unique jti, got %q and %q", a.ID, b.ID)
+	}
+}
+
+func TestRevokeToken(t *testing.T) {
+	setupJWTTest()
+	defer teardownJWTTest()
+	setupRedisTest(t)
+
+	id := uuid.New()
+	revoked, _ := GenerateToken(id, "test@example.com", "Test User")
+	other, _ := GenerateToken(id, "test@example.com", "Test User")
+
+	if err := RevokeToken(revoked); err != nil {
+		t.Fatalf("RevokeToken returned error: %v", err)
+	}
+
+	if _, err := ValidateToken(revoked); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("ValidateToken error = %v, expected %v", err, ErrTokenRevoked)
+	}
+	if _, err := ValidateToken(other); err != nil {
+		t.Errorf("ValidateToken returned error for a token that was not revoked: %v", err)
+	}
+}
+
+func TestRevokeUserTokens(t *testing.T) {
+	setupJWTTest()
+	defer teardownJWTTest()
+	setupRedisTest(t)
+
+	id := uuid.New()
+	access, _ := GenerateToken(id, "test@example.com", "Test User")
+	pair, err := IssueTokenPair(id, "test@example.com", "Test User")
+	if err != nil {
+		t.Fatalf("IssueTokenPair returned error: %v", err)
+	}
+	bystander, _ := GenerateToken(uuid.New(), "other@example.com", "Other User")
+
+	if err := RevokeUserTokens(id.String()); err != nil {
+		t.Fatalf("RevokeUserTokens returned error: %v", err)
+	}
+
+	if _, err := ValidateToken(access); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("ValidateToken error = %v, expected %v", err, ErrTokenRevoked)
+	}
+	if _, err := RotateRefreshToken(pair.RefreshToken); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("RotateRefreshToken error = %v, expected %v", err, ErrTokenRevoked)
+	}
+	if _, err := ValidateToken(bystander); err != nil {
+		t.Errorf("ValidateToken returned error for another user: %v", err)
+	}
+}
+
+func TestRevokeUserTokens_NewTokensUnaffected(t *testing.T) {
+	setupJWTTest()
+	defer teardownJWTTest()
+	setupRedisTest(t)
+
+	id := uuid.New()
+	old, _ := GenerateToken(id, "test@example.com", "Test User")
+	if err := RevokeUserTokens(id.String()); err != nil {
+		t.Fatalf("RevokeUserTokens returned error: %v", err)
+	}
+	token, _ := GenerateToken(id, "test@example.com", "Test User")
+
+	if _, err := ValidateToken(old); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("ValidateToken error = %v, expected %v", err, ErrTokenRevoked)
+	}
+	if _, err := ValidateToken(token); err != nil {
+		t.Errorf("ValidateToken returned error for a token issued right after the revocation: %v", err)
+	}
+}
+
+func TestRevokeUserTokens_LongLivedTokens(t *testing.T) {
+	setupJWTTest()
+	defer teardownJWTTest()
+	mr := setupRedisTest(t)
+
+	id := uuid.New()
+	token, _ := GenerateTokenWithExpiry(id, "test@example.com", "Test User", 30*24*time.Hour)
+	if err := RevokeUserTokens(id.String()); err != nil {
+		t.Fatalf("RevokeUserTokens returned error: %v", err)
+	}
+
+	// The watermark must still be there when the refresh token lifetime has passed
+	mr.FastForward(8 * 24 * time.Hour)
+	if _, err := ValidateToken(token); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("ValidateToken error = %v, expected %v", err, ErrTokenRevoked)
+	}
+}
+
+func TestValidateToken_RedisUnavailable(t *testing.T) {
+	setupJWTTest()
+	defer teardownJWTTest()
+	mr := setupRedisTest(t)
+
+	token, _ := GenerateToken(uuid.New(), "test@example.com", "Test User")
+	mr.Close()
+
+	if _, err := ValidateToken(token); !errors.Is(err, ErrRevocationCheckFailed) {
+		t.Errorf("ValidateToken error = %v, expected %v", err, ErrRevocationCheckFailed)
+	}
+}
@@ -0,0 +1,230 @@
+package jwt
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	response "github.com/writdev-alt/portal-api-shared/responses"
+)
+
+// TokenTypeService marks client-credential tokens issued to internal services
+const TokenTypeService TokenType = "service"
+
+// TokenTypeSnap marks B2B access tokens issued to external SNAP BI partners.
+// They are never accepted as service tokens, so a partner cannot act as an
+// internal client.
+const TokenTypeSnap TokenType = "snap"
+
+// SnapAudience is the only audience of SNAP BI partner tokens
+const SnapAudience = "snap-bi"
+
+var (
+	ErrClientNotFound   = errors.New("service client not found")
+	ErrInvalidClient    = errors.New("invalid service client credentials")
+	ErrScopeNotAllowed  = errors.New("scope not allowed for service client")
+	ErrNotServiceClient = errors.New("token was not issued to a service client")
+	ErrNotSnapPartner   = errors.New("token was not issued to a SNAP partner")
+)
+
+// ServiceClient is an internal service allowed to request tokens with the
+// client credentials grant
+type ServiceClient struct {
+	ID string
+	// SecretHash is the bcrypt hash of the client secret
+	SecretHash string
+	// Scopes lists every scope the client may request, e.g. "wallet:debit"
+	Scopes []string
+}
+
+// ClientStore looks up service clients by ID. FindClient returns
+// ErrClientNotFound for unknown clients.
+type ClientStore interface {
+	FindClient(clientID string) (*ServiceClient, error)
+}
+
+// StaticClients is a ClientStore backed by a fixed set of clients
+type StaticClients map[string]ServiceClient
+
+// NewStaticClients creates a client store from a list of clients
+func NewStaticClients(clients ...ServiceClient) StaticClients {
+	store := make(StaticClients, len(clients))
+	for _, client := range clients {
+		store[client.ID] = client
+	}
+	return store
+}
+
+// FindClient implements ClientStore
+func (s StaticClients) FindClient(clientID string) (*ServiceClient, error) {
+	client, ok := s[clientID]
+	if !ok {
+		return nil, ErrClientNotFound
+	}
+	return &client, nil
+}
+
+// Service identifies the calling service and what it was granted
+type Service struct {
+	ClientID string   `json:"client_id"`
+	Scopes   []string `json:"scopes,omitempty"`
+}
+
+// ServiceClaims represents the claims of a service token
+type ServiceClaims = CustomClaims[Service]
+
+// HasScope reports whether a service token was granted a scope
+func (s Service) HasScope(scope string) bool {
+	return slices.Contains(s.Scopes, scope)
+}
+
+// ServiceToken is the result of a client credentials grant
+type ServiceToken struct {
+	AccessToken string `json:"accessToken"`
+	TokenType   string `json:"tokenType"`
+	ExpiresIn   int64  `json:"expiresIn"` // Token lifetime in seconds
+	Scope       string `json:"scope"`     // Space separated granted scopes
+}
+
+// IssueServiceToken authenticates a service client and issues a token for the
+// requested scopes. Requesting no scopes grants every scope the client is allowed.
+func (m *Manager) IssueServiceToken(store ClientStore, clientID, clientSecret string, scopes ...string) (*ServiceToken, error) {
+	client, err := store.FindClient(clientID)
+	if errors.Is(err, ErrClientNotFound) {
+		return nil, ErrInvalidClient
+	}
+	if err != nil {
+		return nil, err
+	}
+	if client.SecretHash == "" || !ComparePassword(client.SecretHash, clientSecret) {
+		return nil, ErrInvalidClient
+	}
+
+	granted := client.Scopes
+	if len(scopes) > 0 {
+		for _, scope := range scopes {
+			if !slices.Contains(client.Scopes, scope) {
+				return nil, ErrScopeNotAllowed
+			}
+		}
+		granted = scopes
+	}
+
+	return m.IssueServiceTokenFor(client.ID, granted)
+}
+
+// IssueServiceTokenFor issues a service token to an internal client that has
+// already been authenticated by other means
+func (m *Manager) IssueServiceTokenFor(clientID string, scopes []string) (*ServiceToken, error) {
+	return m.issueClientToken(TokenTypeService, serviceSubject(clientID), clientID, scopes)
+}
+
+// ValidateServiceToken validates a token and requires it to be a service token
+func (m *Manager) ValidateServiceToken(tokenString string) (*ServiceClaims, error) {
+	claims, err := parseClaims[Service](m, tokenString, TokenTypeService)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Custom.ClientID == "" || claims.Subject != serviceSubject(claims.Custom.ClientID) {
+		return nil, ErrNotServiceClient
+	}
+	return claims, nil
+}
+
+// IssueSnapToken issues a B2B access token to a SNAP BI partner authenticated by
+// its asymmetric signature. The token has its own type, subject and audience.
+func (m *Manager) IssueSnapToken(clientKey string, scopes []string) (*ServiceToken, error) {
+	return m.ForAudience(SnapAudience).issueClientToken(TokenTypeSnap, snapSubject(clientKey), clientKey, scopes)
+}
+
+// ValidateSnapToken validates a token and requires it to be a SNAP BI partner token
+func (m *Manager) ValidateSnapToken(tokenString string) (*ServiceClaims, error) {
+	claims, err := parseClaims[Service](m.ForAudience(SnapAudience), tokenString, TokenTypeSnap)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Custom.ClientID == "" || claims.Subject != snapSubject(claims.Custom.ClientID) {
+		return nil, ErrNotSnapPartner
+	}
+	return claims, nil
+}
+
+// issueClientToken signs a token for a client rather than a user
+func (m *Manager) issueClientToken(tokenType TokenType, subject, clientID string, scopes []string) (*ServiceToken, error) {
+	claims := newCustomClaims(m, tokenType, Identity{}, Service{ClientID: clientID, Scopes: scopes}, m.serviceTokenExpiry)
+	// Client tokens carry no user, the caller is identified by sub and client_id
+	claims.UUID = ""
+	claims.Subject = subject
+	token, err := m.sign(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ServiceToken{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(m.serviceTokenExpiry.Seconds()),
+		Scope:       strings.Join(scopes, " "),
+	}, nil
+}
+
+// ServiceTokenHandler serves the client credentials grant. Clients authenticate with
+// HTTP Basic auth or the client_id and client_secret form fields and may narrow the
+// granted scopes with a space separated scope field.
+func (m *Manager) ServiceTokenHandler(store ClientStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if grantType := c.PostForm("grant_type"); grantType != "client_credentials" {
+			response.Result(c, http.StatusBadRequest, response.ServiceCodeAuth, response.CaseCodeInvalidValue, nil, "Unsupported grant type")
+			return
+		}
+
+		clientID, clientSecret, ok := c.Request.BasicAuth()
+		if !ok {
+			clientID, clientSecret = c.PostForm("client_id"), c.PostForm("client_secret")
+		}
+
+		token, err := m.IssueServiceToken(store, clientID, clientSecret, strings.Fields(c.PostForm("scope"))...)
+		switch {
+		case errors.Is(err, ErrInvalidClient):
+			response.Result(c, http.StatusUnauthorized, response.ServiceCodeAuth, response.CaseCodeInvalidCredentials, nil, "Invalid client credentials")
+			return
+		case errors.Is(err, ErrScopeNotAllowed):
+			response.ForbiddenError(c, "Scope not allowed")
+			return
+		case err != nil:
+			response.Result(c, http.StatusInternalServerError, response.ServiceCodeAuth, response.CaseCodeInternalError, nil, "Failed to issue token")
+			return
+		}
+
+		response.Result(c, http.StatusOK, response.ServiceCodeAuth, response.CaseCodeSuccess, token, "success")
+	}
+}
+
+// IssueServiceToken issues a service token with the package-level manager
+func IssueServiceToken(store ClientStore, clientID, clientSecret string, scopes ...string) (*ServiceToken, error) {
+	m, err := Default()
+	if err != nil {
+		return nil, err
+	}
+	return m.IssueServiceToken(store, clientID, clientSecret, scopes...)
+}
+
+// ValidateServiceToken validates a service token with the package-level manager
+func ValidateServiceToken(tokenString string) (*ServiceClaims, error) {
+	m, err := Default()
+	if err != nil {
+		return nil, err
+	}
+	return m.ValidateServiceToken(tokenString)
+}
+
+func serviceSubject(clientID string) string {
+	return "service:" + clientID
+}
+
+func snapSubject(clientKey string) string {
+	return "snap:" + clientKey
+}
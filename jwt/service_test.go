@@ -0,0 +1,149 @@
+package jwt
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newTestClients(t *testing.T) StaticClients {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte("wallet-secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("failed to hash secret: %v", err)
+	}
+	return NewStaticClients(ServiceClient{
+		ID:         "wallet",
+		SecretHash: string(hash),
+		Scopes:     []string{"withdrawal:read", "webhook:send"},
+	})
+}
+
+func TestIssueServiceToken(t *testing.T) {
+	t.Parallel()
+
+	m := newTestManager(t, Options{})
+	clients := newTestClients(t)
+
+	token, err := m.IssueServiceToken(clients, "wallet", "wallet-secret", "webhook:send")
+	if err != nil {
+		t.Fatalf("IssueServiceToken returned error: %v", err)
+	}
+	if token.Scope != "webhook:send" || token.ExpiresIn != int64(defaultServiceTokenExpiry.Seconds()) {
+		t.Errorf("unexpected token: %+v", token)
+	}
+
+	claims, err := m.ValidateServiceToken(token.AccessToken)
+	if err != nil {
+		t.Fatalf("ValidateServiceToken returned error: %v", err)
+	}
+	if claims.Custom.ClientID != "wallet" || claims.Subject != "service:wallet" {
+		t.Errorf("unexpected claims: %+v", claims)
+	}
+	if !claims.Custom.HasScope("webhook:send") || claims.Custom.HasScope("withdrawal:read") {
+		t.Errorf("Scopes = %v, expected only the requested scope", claims.Custom.Scopes)
+	}
+
+	// Service tokens are not user access tokens
+	if _, err := m.ValidateToken(token.AccessToken); !errors.Is(err, ErrWrongTokenType) {
+		t.Errorf("ValidateToken error = %v, expected %v", err, ErrWrongTokenType)
+	}
+}
+
+func TestIssueServiceToken_Errors(t *testing.T) {
+	t.Parallel()
+
+	m := newTestManager(t, Options{})
+	clients := newTestClients(t)
+
+	tests := []struct {
+		name     string
+		clientID string
+		secret   string
+		scopes   []string
+		expected error
+	}{
+		{"unknown client", "webhook", "wallet-secret", nil, ErrInvalidClient},
+		{"wrong secret", "wallet", "wrong", nil, ErrInvalidClient},
+		{"scope not allowed", "wallet", "wallet-secret", []string{"wallet:debit"}, ErrScopeNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := m.IssueServiceToken(clients, tt.clientID, tt.secret, tt.scopes...)
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("IssueServiceToken error = %v, expected %v", err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestValidateServiceToken_RejectsAccessToken(t *testing.T) {
+	t.Parallel()
+
+	m := newTestManager(t, Options{})
+	token, _ := m.IssueAccessToken(Identity{Email: "test@example.com"})
+
+	if _, err := m.ValidateServiceToken(token); !errors.Is(err, ErrWrongTokenType) {
+		t.Errorf("ValidateServiceToken error = %v, expected %v", err, ErrWrongTokenType)
+	}
+}
+
+func TestIssueSnapToken(t *testing.T) {
+	t.Parallel()
+
+	m := newTestManager(t, Options{Audience: []string{"portal"}})
+	snap, err := m.IssueSnapToken("partner-1", []string{"transfer"})
+	if err != nil {
+		t.Fatalf("IssueSnapToken returned error: %v", err)
+	}
+	service, _ := m.IssueServiceTokenFor("partner-1", []string{"transfer"})
+
+	claims, err := m.ValidateSnapToken(snap.AccessToken)
+	if err != nil {
+		t.Fatalf("ValidateSnapToken returned error: %v", err)
+	}
+	if claims.Custom.ClientID != "partner-1" || claims.Subject != "snap:partner-1" {
+		t.Errorf("unexpected claims: %+v", claims)
+	}
+
+	// Partner tokens and service tokens are never interchangeable
+	if _, err := m.ValidateServiceToken(snap.AccessToken); err == nil {
+		t.Error("ValidateServiceToken should reject a SNAP partner token")
+	}
+	if _, err := m.ValidateSnapToken(service.AccessToken); err == nil {
+		t.Error("ValidateSnapToken should reject a service token")
+	}
+}
+
+func TestServiceTokenHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := newTestManager(t, Options{})
+
+	router := gin.New()
+	router.POST("/oauth/token", m.ServiceTokenHandler(newTestClients(t)))
+
+	form := url.Values{"grant_type": {"client_credentials"}, "scope": {"withdrawal:read"}}
+	req := httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth("wallet", "wallet-secret")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "accessToken") {
+		t.Errorf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth("wallet", "wrong")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, expected %d", w.Code, http.StatusUnauthorized)
+	}
+}
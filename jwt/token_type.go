@@ -0,0 +1,46 @@
+package jwt
+
+import "errors"
+
+// TokenType identifies what a token may be used for
+type TokenType string
+
+const (
+	TokenTypeAccess  TokenType = "access"
+	TokenTypeRefresh TokenType = "refresh"
+)
+
+var ErrWrongTokenType = errors.New("token type mismatch")
+
+// ValidateTokenOfType validates a token and requires it to be of the given type
+func (m *Manager) ValidateTokenOfType(tokenString string, tokenType TokenType) (*Claims, error) {
+	return parseClaims[struct{}](m, tokenString, tokenType)
+}
+
+// ValidateAccessToken validates a token and requires it to be an access token
+func (m *Manager) ValidateAccessToken(tokenString string) (*Claims, error) {
+	return m.ValidateTokenOfType(tokenString, TokenTypeAccess)
+}
+
+// ValidateRefreshToken validates a token and requires it to be a refresh token
+func (m *Manager) ValidateRefreshToken(tokenString string) (*Claims, error) {
+	return m.ValidateTokenOfType(tokenString, TokenTypeRefresh)
+}
+
+// ValidateAccessToken validates an access token with the package-level manager
+func ValidateAccessToken(tokenString string) (*Claims, error) {
+	m, err := Default()
+	if err != nil {
+		return nil, err
+	}
+	return m.ValidateAccessToken(tokenString)
+}
+
+// ValidateRefreshToken validates a refresh token with the package-level manager
+func ValidateRefreshToken(tokenString string) (*Claims, error) {
+	m, err := Default()
+	if err != nil {
+		return nil, err
+	}
+	return m.ValidateRefreshToken(tokenString)
+}
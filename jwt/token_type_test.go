@@ -0,0 +1,86 @@
+package jwt
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func TestValidateToken_RejectsRefreshToken(t *testing.T) {
+	t.Parallel()
+
+	m := newTestManager(t, Options{})
+	refresh, err := m.GenerateRefreshToken(uuid.New(), "test@example.com", "Test User")
+	if err != nil {
+		t.Fatalf("GenerateRefreshToken returned error: %v", err)
+	}
+
+	if _, err := m.ValidateToken(refresh); !errors.Is(err, ErrWrongTokenType) {
+		t.Errorf("ValidateToken error = %v, expected %v", err, ErrWrongTokenType)
+	}
+	claims, err := m.ValidateRefreshToken(refresh)
+	if err != nil {
+		t.Fatalf("ValidateRefreshToken returned error: %v", err)
+	}
+	if claims.TokenType != TokenTypeRefresh {
+		t.Errorf("TokenType = %s, expected %s", claims.TokenType, TokenTypeRefresh)
+	}
+}
+
+func TestValidateRefreshToken_RejectsAccessToken(t *testing.T) {
+	t.Parallel()
+
+	m := newTestManager(t, Options{})
+	access, err := m.GenerateToken(uuid.New(), "test@example.com", "Test User")
+	if err != nil {
+		t.Fatalf("GenerateToken returned error: %v", err)
+	}
+
+	if _, err := m.ValidateRefreshToken(access); !errors.Is(err, ErrWrongTokenType) {
+		t.Errorf("ValidateRefreshToken error = %v, expected %v", err, ErrWrongTokenType)
+	}
+}
+
+func TestValidateTokenOfType_LegacyUntypedToken(t *testing.T) {
+	t.Parallel()
+
+	secret := []byte("test-secret-key-for-unit-testing")
+	m := newTestManager(t, Options{Secret: secret})
+
+	// Tokens minted before typ existed
+	legacy := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{UUID: uuid.NewString()})
+	tokenString, _ := legacy.SignedString(secret)
+
+	if _, err := m.ValidateAccessToken(tokenString); err != nil {
+		t.Errorf("ValidateAccessToken returned error for legacy token: %v", err)
+	}
+	if _, err := m.ValidateRefreshToken(tokenString); !errors.Is(err, ErrWrongTokenType) {
+		t.Errorf("ValidateRefreshToken error = %v, expected %v", err, ErrWrongTokenType)
+	}
+}
+
+func TestForAudience(t *testing.T) {
+	t.Parallel()
+
+	m := newTestManager(t, Options{Issuer: "portal"})
+	admin := m.ForAudience("admin-portal")
+	merchant := m.ForAudience("merchant-api")
+
+	token, err := admin.GenerateToken(uuid.New(), "test@example.com", "Test User")
+	if err != nil {
+		t.Fatalf("GenerateToken returned error: %v", err)
+	}
+
+	claims, err := admin.ValidateAccessToken(token)
+	if err != nil {
+		t.Fatalf("ValidateAccessToken returned error: %v", err)
+	}
+	if claims.Issuer != "portal" || len(claims.Audience) != 1 || claims.Audience[0] != "admin-portal" {
+		t.Errorf("unexpected iss/aud: %s %v", claims.Issuer, claims.Audience)
+	}
+	if _, err := merchant.ValidateAccessToken(token); !errors.Is(err, jwt.ErrTokenInvalidAudience) {
+		t.Errorf("ValidateAccessToken error = %v, expected %v", err, jwt.ErrTokenInvalidAudience)
+	}
+}
@@ -0,0 +1,54 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+)
+
+// requestIDKey is the context key of the request ID
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying a request ID. Entries logged with the
+// context, e.g. through InfoContext, include it as requestId.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request ID carried by a context, if any
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// DebugContext logs a message at level Debug with fields and the request ID of ctx.
+func DebugContext(ctx context.Context, msg string, fields Fields) {
+	logFields(ctx, slog.LevelDebug, msg, fields)
+}
+
+// InfoContext logs a message at level Info with fields and the request ID of ctx.
+func InfoContext(ctx context.Context, msg string, fields Fields) {
+	logFields(ctx, slog.LevelInfo, msg, fields)
+}
+
+// WarnContext logs a message at level Warn with fields and the request ID of ctx.
+func WarnContext(ctx context.Context, msg string, fields Fields) {
+	logFields(ctx, slog.LevelWarn, msg, fields)
+}
+
+// ErrorContext logs a message at level Error with fields and the request ID of ctx.
+func ErrorContext(ctx context.Context, msg string, fields Fields) {
+	logFields(ctx, slog.LevelError, msg, fields)
+}
+
+func logFields(ctx context.Context, level slog.Level, msg string, fields Fields) {
+	if logger.Enabled(ctx, level) {
+		attrs := make([]slog.Attr, 0, len(fields))
+		for k, v := range fields {
+			attrs = append(attrs, slog.Any(k, v))
+		}
+		logger.LogAttrs(ctx, level, msg, attrs...)
+	}
+}
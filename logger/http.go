@@ -0,0 +1,20 @@
+package logger
+
+// HTTPRequestKey is the field holding an *HTTPRequest. Entries with it are shown
+// as request logs in Cloud Logging.
+const HTTPRequestKey = "httpRequest"
+
+// HTTPRequest is Cloud Logging's httpRequest object
+type HTTPRequest struct {
+	RequestMethod string `json:"requestMethod,omitempty"`
+	RequestURL    string `json:"requestUrl,omitempty"`
+	RequestSize   int64  `json:"requestSize,omitempty,string"`
+	Status        int    `json:"status,omitempty"`
+	ResponseSize  int64  `json:"responseSize,omitempty,string"`
+	UserAgent     string `json:"userAgent,omitempty"`
+	RemoteIP      string `json:"remoteIp,omitempty"`
+	ServerIP      string `json:"serverIp,omitempty"`
+	Referer       string `json:"referer,omitempty"`
+	Latency       string `json:"latency,omitempty"` // Seconds with an "s" suffix, e.g. "0.125s"
+	Protocol      string `json:"protocol,omitempty"`
+}
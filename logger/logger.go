@@ -12,7 +12,11 @@ import (
 	"time"
 )
 
-var logger *slog.Logger
+var (
+	logger    *slog.Logger
+	logLevel  = slog.LevelInfo
+	logWriter io.Writer
+)
 
 // gcpSeverity maps slog levels to Google Cloud Logging severity levels
 func gcpSeverity(level slog.Level) string {
@@ -42,7 +46,9 @@ type gcpLogEntry struct {
 	Severity       string                 `json:"severity"`
 	Time           string                 `json:"time"`
 	Message        string                 `json:"message"`
+	RequestID      string                 `json:"requestId,omitempty"`
 	SourceLocation *sourceLocation        `json:"sourceLocation,omitempty"`
+	HTTPRequest    *HTTPRequest           `json:"httpRequest,omitempty"`
 	Fields         map[string]interface{} `json:"fields,omitempty"`
 }
 
@@ -68,6 +74,8 @@ func (h *gcpHandler) Handle(ctx context.Context, record slog.Record) error {
 		Severity: gcpSeverity(record.Level),
 		Time:     record.Time.Format(time.RFC3339Nano),
 		Message:  record.Message,
+		// Entries logged with a request context carry its request ID
+		RequestID: RequestIDFromContext(ctx),
 	}
 
 	// Add source location if available
@@ -86,6 +94,11 @@ func (h *gcpHandler) Handle(ctx context.Context, record slog.Record) error {
 	// Collect attributes
 	fields := make(map[string]interface{})
 	record.Attrs(func(a slog.Attr) bool {
+		// Cloud Logging only recognises httpRequest at the top level of the entry
+		if httpRequest, ok := a.Value.Any().(*HTTPRequest); ok && a.Key == HTTPRequestKey {
+			entry.HTTPRequest = httpRequest
+			return true
+		}
 		fields[a.Key] = a.Value.Any()
 		return true
 	})
@@ -117,16 +130,20 @@ func (h *gcpHandler) WithGroup(name string) slog.Handler {
 }
 
 func init() {
-	writer := getWriter()
-	handler := newGCPHandler(writer, slog.LevelInfo)
-	logger = slog.New(handler)
+	logWriter = getWriter()
+	logger = slog.New(newGCPHandler(logWriter, logLevel))
 }
 
 // SetLogLevel sets the log level for the logger
 func SetLogLevel(level slog.Level) {
-	writer := getWriter()
-	handler := newGCPHandler(writer, level)
-	logger = slog.New(handler)
+	logLevel = level
+	logger = slog.New(newGCPHandler(logWriter, level))
+}
+
+// SetOutput replaces where log entries are written, e.g. os.Stdout on Cloud Run
+func SetOutput(writer io.Writer) {
+	logWriter = writer
+	logger = slog.New(newGCPHandler(writer, logLevel))
 }
 
 // Fields is a map of key-value pairs for structured logging
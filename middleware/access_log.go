@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/writdev-alt/portal-api-shared/logger"
+	response "github.com/writdev-alt/portal-api-shared/responses"
+)
+
+// AccessLogConfig configures AccessLog
+type AccessLogConfig struct {
+	// SkipPaths are not logged, e.g. "/health"
+	SkipPaths []string
+	// Skip decides per request whether to skip logging, e.g. successful probes only
+	Skip func(c *gin.Context) bool
+}
+
+// AccessLog logs every request through the logger package with Cloud Logging's
+// httpRequest object, the caller's identity and the response code. 5xx responses
+// are logged as errors and 4xx as warnings.
+func AccessLog(config AccessLogConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		if slices.Contains(config.SkipPaths, c.Request.URL.Path) || (config.Skip != nil && config.Skip(c)) {
+			return
+		}
+
+		status := c.Writer.Status()
+		httpRequest := &logger.HTTPRequest{
+			RequestMethod: c.Request.Method,
+			RequestURL:    c.Request.URL.RequestURI(),
+			Status:        status,
+			UserAgent:     c.Request.UserAgent(),
+			RemoteIP:      ClientIP(c),
+			Referer:       c.Request.Referer(),
+			Latency:       fmt.Sprintf("%.9fs", time.Since(start).Seconds()),
+			Protocol:      c.Request.Proto,
+		}
+		if c.Request.ContentLength > 0 {
+			httpRequest.RequestSize = c.Request.ContentLength
+		}
+		if size := c.Writer.Size(); size > 0 {
+			httpRequest.ResponseSize = int64(size)
+		}
+
+		fields := logger.Fields{
+			logger.HTTPRequestKey: httpRequest,
+			"route":               c.FullPath(),
+		}
+		if code, exists := c.Get(response.ContextKeyResponseCode); exists {
+			fields["code"] = code
+		}
+		for key, value := range map[string]string{
+			"user_id":     GetUserID(c),
+			"merchant_id": GetMerchantID(c),
+			"service_id":  GetServiceID(c),
+		} {
+			if value != "" {
+				fields[key] = value
+			}
+		}
+		if len(c.Errors) > 0 {
+			fields["errors"] = c.Errors.String()
+		}
+
+		message := fmt.Sprintf("%s %s %d", c.Request.Method, c.Request.URL.Path, status)
+		switch {
+		case status >= http.StatusInternalServerError:
+			logger.ErrorContext(c.Request.Context(), message, fields)
+		case status >= http.StatusBadRequest:
+			logger.WarnContext(c.Request.Context(), message, fields)
+		default:
+			logger.InfoContext(c.Request.Context(), message, fields)
+		}
+	}
+}
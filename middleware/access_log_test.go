@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/writdev-alt/portal-api-shared/logger"
+	response "github.com/writdev-alt/portal-api-shared/responses"
+)
+
+func TestAccessLog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var out bytes.Buffer
+	logger.SetOutput(&out)
+	t.Cleanup(func() { logger.SetOutput(os.Stderr) })
+
+	router := gin.New()
+	router.Use(AccessLog(AccessLogConfig{SkipPaths: []string{"/health"}}))
+	router.GET("/health", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.POST("/merchants/:id/withdrawals", func(c *gin.Context) {
+		c.Set(ContextKeyMerchantID, c.Param("id"))
+		response.Result(c, http.StatusForbidden, response.ServiceCodeWithdrawal, response.CaseCodeLimitExceeded, nil, "Limit exceeded")
+	})
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/health", nil),
+		httptest.NewRequest(http.MethodPost, "/merchants/m-1/withdrawals?dry_run=1", strings.NewReader(`{"amount":1}`)),
+	} {
+		req.RemoteAddr = "203.0.113.5:1234"
+		req.Header.Set("User-Agent", "merchant-sdk/1.0")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("expected one entry, got %d: %s", len(lines), out.String())
+	}
+	var entry struct {
+		Severity    string                 `json:"severity"`
+		HTTPRequest map[string]interface{} `json:"httpRequest"`
+		Fields      map[string]interface{} `json:"fields"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("failed to decode entry: %v", err)
+	}
+
+	if entry.Severity != "WARNING" {
+		t.Errorf("severity = %s", entry.Severity)
+	}
+	want := map[string]interface{}{
+		"requestMethod": "POST",
+		"requestUrl":    "/merchants/m-1/withdrawals?dry_run=1",
+		"status":        float64(http.StatusForbidden),
+		"requestSize":   "12",
+		"remoteIp":      "203.0.113.5",
+		"userAgent":     "merchant-sdk/1.0",
+	}
+	for key, value := range want {
+		if entry.HTTPRequest[key] != value {
+			t.Errorf("httpRequest.%s = %v, want %v", key, entry.HTTPRequest[key], value)
+		}
+	}
+	if latency, _ := entry.HTTPRequest["latency"].(string); !strings.HasSuffix(latency, "s") {
+		t.Errorf("httpRequest.latency = %v", entry.HTTPRequest["latency"])
+	}
+
+	code := response.BuildResponseCode(http.StatusForbidden, response.ServiceCodeWithdrawal, response.CaseCodeLimitExceeded)
+	if entry.Fields["code"] != float64(code) || entry.Fields["merchant_id"] != "m-1" || entry.Fields["route"] != "/merchants/:id/withdrawals" {
+		t.Errorf("fields = %v", entry.Fields)
+	}
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/writdev-alt/portal-api-shared/apikey"
+	response "github.com/writdev-alt/portal-api-shared/responses"
+)
+
+// APIKeyHeader carries merchant API keys
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyAuth authenticates merchants by their API key, requires every listed scope
+// and stores the key and its merchant in the context
+func APIKeyAuth(svc *apikey.Service, scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		plaintext := c.GetHeader(APIKeyHeader)
+		if plaintext == "" {
+			response.UnauthorizedError(c, "API key required")
+			c.Abort()
+			return
+		}
+
+		key, err := svc.Authenticate(plaintext)
+		switch {
+		case errors.Is(err, apikey.ErrKeyExpired):
+			response.Result(c, http.StatusUnauthorized, response.ServiceCodeAuth, response.CaseCodeTokenExpired, nil, "API key has expired")
+			c.Abort()
+			return
+		case errors.Is(err, apikey.ErrInvalidKey), errors.Is(err, apikey.ErrKeyRevoked):
+			response.Result(c, http.StatusUnauthorized, response.ServiceCodeAuth, response.CaseCodeInvalidToken, nil, "Invalid API key")
+			c.Abort()
+			return
+		case err != nil:
+			response.Result(c, http.StatusInternalServerError, response.ServiceCodeAuth, response.CaseCodeInternalError, nil, "Failed to verify API key")
+			c.Abort()
+			return
+		}
+
+		for _, scope := range scopes {
+			if !key.HasScope(scope) {
+				response.ForbiddenError(c, "Missing scope: "+scope)
+				c.Abort()
+				return
+			}
+		}
+
+		c.Set(ContextKeyAPIKey, key)
+		c.Set(ContextKeyMerchantID, key.MerchantID)
+
+		c.Next()
+	}
+}
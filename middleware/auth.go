@@ -1,57 +1,53 @@
 package middleware
 
 import (
+	"crypto/subtle"
 	"net/http"
 	"os"
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
+
+	sharedjwt "github.com/writdev-alt/portal-api-shared/jwt"
+	response "github.com/writdev-alt/portal-api-shared/responses"
 )
 
-// AuthMiddleware validates JWT token
+// AuthMiddleware validates the bearer access token with the package-level jwt manager
+// (see jwt.NewManagerFromEnv) and stores its claims in the context
 func AuthMiddleware() gin.HandlerFunc {
+	return Auth(nil)
+}
+
+// Auth validates the bearer access token with a manager and stores its claims in
+// the context. A nil manager uses the package-level one.
+func Auth(m *sharedjwt.Manager) gin.HandlerFunc {
+	return AuthCustom[struct{}](m)
+}
+
+// AuthCustom is Auth for tokens issued with jwt.IssueCustomAccessToken. The typed
+// claims are read back with CurrentCustomClaims[T]; CurrentClaims and the other
+// accessors keep working.
+func AuthCustom[T any](m *sharedjwt.Manager) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
+		tokenString, ok := bearerToken(c)
+		if !ok {
+			response.UnauthorizedError(c, "Authorization header required")
 			c.Abort()
 			return
 		}
 
-		// Extract token
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format"})
-			c.Abort()
+		manager, ok := resolveManager(c, m)
+		if !ok {
 			return
 		}
 
-		tokenString := parts[1]
-
-		// Get JWT secret from environment
-		jwtSecret := os.Getenv("JWT_SECRET")
-		if jwtSecret == "" {
-			jwtSecret = "your-secret-key" // Default for development
-		}
-
-		// Parse token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			return []byte(jwtSecret), nil
-		})
-
-		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-			c.Abort()
+		claims, err := sharedjwt.ValidateCustomAccessToken[T](manager, tokenString)
+		if err != nil {
+			abortTokenError(c, err, "Invalid token")
 			return
 		}
 
-		// Extract claims
-		if claims, ok := token.Claims.(jwt.MapClaims); ok {
-			c.Set("email", claims["email"])
-			c.Set("user_id", claims["user_id"])
-			c.Set("role", claims["role"])
-		}
+		setClaims(c, claims)
 
 		c.Next()
 	}
@@ -60,9 +56,8 @@ func AuthMiddleware() gin.HandlerFunc {
 // AdminMiddleware checks if user is admin
 func AdminMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		role, exists := c.Get("role")
-		if !exists || role != "admin" {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		if !HasRole(c, "admin") {
+			response.ForbiddenError(c, "Admin access required")
 			c.Abort()
 			return
 		}
@@ -70,12 +65,15 @@ func AdminMiddleware() gin.HandlerFunc {
 	}
 }
 
-// APIKeyMiddleware validates API key
+// APIKeyMiddleware validates the X-API-Key header against the API_KEY env var
+//
+// Deprecated: a single shared key cannot be scoped, rotated or traced to a caller.
+// Use APIKeyAuth for merchants and ServiceAuthMiddleware for internal services.
 func APIKeyMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		apiKey := c.GetHeader("X-API-Key")
+		apiKey := c.GetHeader(APIKeyHeader)
 		if apiKey == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "API key required"})
+			response.UnauthorizedError(c, "API key required")
 			c.Abort()
 			return
 		}
@@ -83,14 +81,14 @@ func APIKeyMiddleware() gin.HandlerFunc {
 		// Get expected API key from environment
 		expectedKey := os.Getenv("API_KEY")
 		if expectedKey == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "API key not configured"})
+			response.Result(c, http.StatusServiceUnavailable, response.ServiceCodeAuth, response.CaseCodeConfigurationError, nil, "API key not configured")
 			c.Abort()
 			return
 		}
 
-		// Validate API key
-		if apiKey != expectedKey {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+		// Validate API key in constant time
+		if subtle.ConstantTimeCompare([]byte(apiKey), []byte(expectedKey)) != 1 {
+			response.Result(c, http.StatusUnauthorized, response.ServiceCodeAuth, response.CaseCodeInvalidToken, nil, "Invalid API key")
 			c.Abort()
 			return
 		}
@@ -98,3 +96,45 @@ func APIKeyMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// bearerToken extracts the token from an "Authorization: Bearer <token>" header
+func bearerToken(c *gin.Context) (string, bool) {
+	parts := strings.Fields(c.GetHeader("Authorization"))
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+		return "", false
+	}
+	return parts[1], true
+}
+
+// resolveManager returns m, or the package-level manager when m is nil. It aborts
+// the request when the package-level manager cannot be configured.
+func resolveManager(c *gin.Context, m *sharedjwt.Manager) (*sharedjwt.Manager, bool) {
+	if m != nil {
+		return m, true
+	}
+	m, err := sharedjwt.Default()
+	if err != nil {
+		response.Result(c, http.StatusServiceUnavailable, response.ServiceCodeAuth, response.CaseCodeConfigurationError, nil, "Authentication is not configured")
+		c.Abort()
+		return nil, false
+	}
+	return m, true
+}
+
+// abortTokenError answers a failed token validation, telling expired tokens apart
+// from invalid ones so clients know when to refresh
+func abortTokenError(c *gin.Context, err error, message string) {
+	caseCode := sharedjwt.CaseCode(err)
+	status := http.StatusUnauthorized
+	switch caseCode {
+	case response.CaseCodeTokenExpired:
+		message = "Token has expired"
+	case response.CaseCodeSessionExpired:
+		message = "Session has expired"
+	case response.CaseCodeServiceUnavailable:
+		status = http.StatusServiceUnavailable
+		message = "Authentication is temporarily unavailable"
+	}
+	response.Result(c, status, response.ServiceCodeAuth, caseCode, nil, message)
+	c.Abort()
+}
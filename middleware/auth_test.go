@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	sharedjwt "github.com/writdev-alt/portal-api-shared/jwt"
+	response "github.com/writdev-alt/portal-api-shared/responses"
+)
+
+func newTestManager(t *testing.T, clock func() time.Time) *sharedjwt.Manager {
+	t.Helper()
+	m, err := sharedjwt.NewManager(sharedjwt.Options{
+		Secret: []byte("test-secret-key-for-unit-testing"),
+		Clock:  clock,
+	})
+	if err != nil {
+		t.Fatalf("NewManager returned error: %v", err)
+	}
+	return m
+}
+
+func performRequest(router *gin.Engine, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func responseCaseCode(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body response.CommonResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	_, _, caseCode := response.ParseResponseCode(body.Code)
+	return caseCode
+}
+
+func TestAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	now := time.Now()
+	m := newTestManager(t, func() time.Time { return now })
+
+	router := gin.New()
+	router.GET("/", Auth(m), func(c *gin.Context) {
+		claims, ok := CurrentClaims(c)
+		if !ok || claims.UUID != GetUserID(c) || GetMerchantID(c) != "merchant-1" || !HasRole(c, "admin") || c.GetString("role") != "admin" {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	token, err := m.IssueAccessToken(sharedjwt.Identity{UUID: uuid.New(), Roles: []string{"admin", "finance"}, MerchantID: "merchant-1"})
+	if err != nil {
+		t.Fatalf("IssueAccessToken returned error: %v", err)
+	}
+
+	if w := performRequest(router, token); w.Code != http.StatusNoContent {
+		t.Errorf("status = %d, expected %d", w.Code, http.StatusNoContent)
+	}
+
+	w := performRequest(router, "")
+	if w.Code != http.StatusUnauthorized || responseCaseCode(t, w) != response.CaseCodeUnauthorized {
+		t.Errorf("missing token: status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	w = performRequest(router, "invalid.token.here")
+	if w.Code != http.StatusUnauthorized || responseCaseCode(t, w) != response.CaseCodeInvalidToken {
+		t.Errorf("invalid token: status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	now = now.Add(2 * time.Hour)
+	w = performRequest(router, token)
+	if w.Code != http.StatusUnauthorized || responseCaseCode(t, w) != response.CaseCodeTokenExpired {
+		t.Errorf("expired token: status = %d, body = %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAuth_RejectsOtherSecrets(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := newTestManager(t, nil)
+	other, err := sharedjwt.NewManager(sharedjwt.Options{Secret: []byte("your-secret-key")})
+	if err != nil {
+		t.Fatalf("NewManager returned error: %v", err)
+	}
+
+	router := gin.New()
+	router.GET("/", Auth(m), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	token, _ := other.GenerateToken(uuid.New(), "test@example.com", "Test User")
+	if w := performRequest(router, token); w.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, expected %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestAuthCustom(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := newTestManager(t, nil)
+
+	type tenantClaims struct {
+		Tenant string `json:"tenant"`
+		Tier   int    `json:"tier"`
+	}
+
+	router := gin.New()
+	router.GET("/", AuthCustom[tenantClaims](m), func(c *gin.Context) {
+		custom, ok := CurrentCustomClaims[tenantClaims](c)
+		if !ok || custom.Custom.Tenant != "acme" || custom.Custom.Tier != 2 {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		if _, ok := CurrentCustomClaims[struct{}](c); ok {
+			c.Status(http.StatusConflict)
+			return
+		}
+		claims, ok := CurrentClaims(c)
+		if !ok || claims.UUID != custom.UUID || GetMerchantID(c) != "merchant-1" {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	identity := sharedjwt.Identity{UUID: uuid.New(), MerchantID: "merchant-1"}
+	token, err := sharedjwt.IssueCustomAccessToken(m, identity, tenantClaims{Tenant: "acme", Tier: 2})
+	if err != nil {
+		t.Fatalf("IssueCustomAccessToken returned error: %v", err)
+	}
+
+	if w := performRequest(router, token); w.Code != http.StatusNoContent {
+		t.Errorf("status = %d, expected %d", w.Code, http.StatusNoContent)
+	}
+
+	if w := performRequest(router, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("missing token: status = %d, expected %d", w.Code, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,193 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/writdev-alt/portal-api-shared/logger"
+)
+
+// ClientIPConfig configures a ClientIPResolver
+type ClientIPConfig struct {
+	// TrustedProxies lists the IPs and CIDRs of our own proxies and load balancers
+	TrustedProxies []string
+	// TrustCloudflare also trusts the Cloudflare IP ranges (see CLOUDFLARE_IPS_FILE)
+	TrustCloudflare bool
+}
+
+// ClientIPResolver resolves the client IP of a request. CF-Connecting-IP,
+// X-Forwarded-For and X-Real-IP are only honoured when they were set by a trusted
+// proxy, so clients cannot spoof their address by sending the headers themselves.
+type ClientIPResolver struct {
+	trusted         []*net.IPNet
+	trustCloudflare bool
+}
+
+// NewClientIPResolver creates a resolver, rejecting invalid proxy addresses
+func NewClientIPResolver(config ClientIPConfig) (*ClientIPResolver, error) {
+	r := &ClientIPResolver{trustCloudflare: config.TrustCloudflare}
+	for _, proxy := range config.TrustedProxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", proxy)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			r.trusted = append(r.trusted, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		r.trusted = append(r.trusted, network)
+	}
+	return r, nil
+}
+
+// ClientIPConfigFromEnv reads TRUSTED_PROXIES (comma separated IPs and CIDRs) and
+// TRUST_CLOUDFLARE (defaults to true)
+func ClientIPConfigFromEnv() ClientIPConfig {
+	config := ClientIPConfig{TrustCloudflare: true}
+	if v := strings.TrimSpace(os.Getenv("TRUSTED_PROXIES")); v != "" {
+		config.TrustedProxies = strings.Split(v, ",")
+	}
+	if v := strings.TrimSpace(os.Getenv("TRUST_CLOUDFLARE")); v == "false" || v == "0" {
+		config.TrustCloudflare = false
+	}
+	return config
+}
+
+var (
+	clientIPMu       sync.RWMutex
+	clientIPResolver *ClientIPResolver
+)
+
+// DefaultClientIPResolver returns the resolver used by the middleware, building it
+// from the environment (see ClientIPConfigFromEnv) on first use. Invalid proxies
+// are logged and the resolver falls back to trusting Cloudflare only.
+func DefaultClientIPResolver() *ClientIPResolver {
+	clientIPMu.RLock()
+	r := clientIPResolver
+	clientIPMu.RUnlock()
+	if r != nil {
+		return r
+	}
+
+	clientIPMu.Lock()
+	defer clientIPMu.Unlock()
+	if clientIPResolver == nil {
+		config := ClientIPConfigFromEnv()
+		r, err := NewClientIPResolver(config)
+		if err != nil {
+			logger.Errorf("Invalid TRUSTED_PROXIES, trusting Cloudflare only: %v", err)
+			r = &ClientIPResolver{trustCloudflare: config.TrustCloudflare}
+		}
+		clientIPResolver = r
+	}
+	return clientIPResolver
+}
+
+// SetClientIPResolver replaces the resolver used by the middleware. Passing nil makes
+// the next call reload the configuration from the environment.
+func SetClientIPResolver(r *ClientIPResolver) {
+	clientIPMu.Lock()
+	defer clientIPMu.Unlock()
+	clientIPResolver = r
+}
+
+// ClientIP returns the client IP of a request using the default resolver
+func ClientIP(c *gin.Context) string {
+	ip, _ := DefaultClientIPResolver().Resolve(c.Request)
+	return ip
+}
+
+// Resolve returns the client IP and whether the request reached us through
+// Cloudflare. Starting at the immediate peer it walks X-Forwarded-For from the
+// right, skipping trusted proxies; the first untrusted address is the client.
+// A trusted Cloudflare hop supplies the client IP through CF-Connecting-IP.
+func (r *ClientIPResolver) Resolve(req *http.Request) (string, bool) {
+	peer := parseHop(req.RemoteAddr)
+	if peer == nil {
+		return req.RemoteAddr, false
+	}
+	hops := forwardedFor(req)
+
+	ip := peer
+	viaCloudflare := false
+	for {
+		cloudflare := isCloudflareIP(ip.String(), cloudflareIPRanges())
+		viaCloudflare = viaCloudflare || cloudflare
+
+		if cloudflare && r.trustCloudflare {
+			if cfIP := net.ParseIP(strings.TrimSpace(req.Header.Get("CF-Connecting-IP"))); cfIP != nil {
+				return cfIP.String(), true
+			}
+		} else if !r.isTrustedProxy(ip) {
+			return ip.String(), viaCloudflare
+		}
+
+		if len(hops) == 0 {
+			// X-Real-IP is only meaningful when set by the immediate peer
+			if ip.Equal(peer) {
+				if realIP := net.ParseIP(strings.TrimSpace(req.Header.Get("X-Real-IP"))); realIP != nil {
+					return realIP.String(), viaCloudflare
+				}
+			}
+			return ip.String(), viaCloudflare
+		}
+
+		next := parseHop(hops[len(hops)-1])
+		hops = hops[:len(hops)-1]
+		if next == nil {
+			// A malformed entry cannot be attributed, stop at the last trusted hop
+			return ip.String(), viaCloudflare
+		}
+		ip = next
+	}
+}
+
+// isTrustedProxy reports whether an address is one of our proxies
+func (r *ClientIPResolver) isTrustedProxy(ip net.IP) bool {
+	for _, network := range r.trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedFor returns every X-Forwarded-For entry, left to right
+func forwardedFor(req *http.Request) []string {
+	var hops []string
+	for _, header := range req.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(header, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+	return hops
+}
+
+// parseHop parses an address with or without a port
+func parseHop(addr string) net.IP {
+	addr = strings.TrimSpace(addr)
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	return net.ParseIP(strings.Trim(addr, "[]"))
+}
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestClientIPResolver_Resolve(t *testing.T) {
+	resolver, err := NewClientIPResolver(ClientIPConfig{
+		TrustedProxies:  []string{"10.0.0.0/8", "192.0.2.10"},
+		TrustCloudflare: true,
+	})
+	if err != nil {
+		t.Fatalf("NewClientIPResolver returned error: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		remoteAddr    string
+		headers       map[string]string
+		want          string
+		viaCloudflare bool
+	}{
+		{"direct client", "203.0.113.5:1234", nil, "203.0.113.5", false},
+		{"spoofed headers from untrusted peer", "203.0.113.5:1234", map[string]string{"CF-Connecting-IP": "1.1.1.1", "X-Forwarded-For": "1.1.1.1", "X-Real-IP": "1.1.1.1"}, "203.0.113.5", false},
+		{"trusted proxy", "10.0.0.2:1234", map[string]string{"X-Forwarded-For": "198.51.100.7"}, "198.51.100.7", false},
+		{"forged leftmost entry", "10.0.0.2:1234", map[string]string{"X-Forwarded-For": "1.1.1.1, 198.51.100.7"}, "198.51.100.7", false},
+		{"proxy chain", "10.0.0.2:1234", map[string]string{"X-Forwarded-For": "198.51.100.7, 192.0.2.10, 10.1.1.1"}, "198.51.100.7", false},
+		{"X-Real-IP from trusted proxy", "192.0.2.10:1234", map[string]string{"X-Real-IP": "198.51.100.7"}, "198.51.100.7", false},
+		{"Cloudflare peer", "173.245.48.1:1234", map[string]string{"CF-Connecting-IP": "198.51.100.7", "X-Forwarded-For": "1.1.1.1"}, "198.51.100.7", true},
+		{"Cloudflare behind load balancer", "10.0.0.2:1234", map[string]string{"CF-Connecting-IP": "198.51.100.7", "X-Forwarded-For": "198.51.100.7, 173.245.48.1"}, "198.51.100.7", true},
+		{"Cloudflare spoofed through load balancer", "10.0.0.2:1234", map[string]string{"CF-Connecting-IP": "1.1.1.1", "X-Forwarded-For": "173.245.48.1, 203.0.113.5"}, "203.0.113.5", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			got, viaCloudflare := resolver.Resolve(req)
+			if got != tt.want || viaCloudflare != tt.viaCloudflare {
+				t.Errorf("Resolve() = %s, %v, want %s, %v", got, viaCloudflare, tt.want, tt.viaCloudflare)
+			}
+		})
+	}
+}
+
+func TestIPWhitelist_IgnoresSpoofedHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetClientIPResolver(&ClientIPResolver{trustCloudflare: true})
+	t.Cleanup(func() { SetClientIPResolver(nil) })
+
+	router := gin.New()
+	router.GET("/", IPWhitelist(IPWhitelistConfig{AllowedIPs: []string{"198.51.100.7"}}), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	router.GET("/cf", CloudflareIPWhitelist(), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	send := func(path, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("CF-Connecting-IP", "198.51.100.7")
+		req.Header.Set("X-Forwarded-For", "198.51.100.7")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := send("/", "203.0.113.5:1234"); w.Code != http.StatusForbidden {
+		t.Errorf("spoofed whitelist: status = %d", w.Code)
+	}
+	if w := send("/", "173.245.48.1:1234"); w.Code != http.StatusNoContent {
+		t.Errorf("whitelisted through Cloudflare: status = %d", w.Code)
+	}
+	if w := send("/cf", "203.0.113.5:1234"); w.Code != http.StatusForbidden {
+		t.Errorf("spoofed Cloudflare: status = %d", w.Code)
+	}
+	if w := send("/cf", "173.245.48.1:1234"); w.Code != http.StatusNoContent {
+		t.Errorf("Cloudflare: status = %d", w.Code)
+	}
+}
@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newTestCertificate creates a certificate signed by parent, or a self-signed CA when parent is nil
+func newTestCertificate(t *testing.T, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func TestCloudflareOriginPull(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ca, caKey := newTestCertificate(t, "origin-pull-ca", nil, nil)
+	client, _ := newTestCertificate(t, "zone", ca, caKey)
+	otherCA, otherKey := newTestCertificate(t, "other-ca", nil, nil)
+	forged, _ := newTestCertificate(t, "zone", otherCA, otherKey)
+
+	cas := x509.NewCertPool()
+	cas.AddCert(ca)
+	sum := sha256.Sum256(client.Raw)
+
+	send := func(config CloudflareOriginPullConfig, certs ...*x509.Certificate) int {
+		router := gin.New()
+		router.GET("/", CloudflareOriginPull(config), func(c *gin.Context) {
+			c.Status(http.StatusNoContent)
+		})
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.TLS = &tls.ConnectionState{PeerCertificates: certs}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := send(CloudflareOriginPullConfig{CAs: cas}, client); code != http.StatusNoContent {
+		t.Errorf("valid certificate: status = %d", code)
+	}
+	if code := send(CloudflareOriginPullConfig{CAs: cas}); code != http.StatusForbidden {
+		t.Errorf("no certificate: status = %d", code)
+	}
+	if code := send(CloudflareOriginPullConfig{CAs: cas}, forged); code != http.StatusForbidden {
+		t.Errorf("certificate from another CA: status = %d", code)
+	}
+	if code := send(CloudflareOriginPullConfig{CAs: cas, Fingerprints: []string{hex.EncodeToString(sum[:])}}, client); code != http.StatusNoContent {
+		t.Errorf("pinned certificate: status = %d", code)
+	}
+	if code := send(CloudflareOriginPullConfig{CAs: cas, Fingerprints: []string{"00"}}, client); code != http.StatusForbidden {
+		t.Errorf("certificate not pinned: status = %d", code)
+	}
+	expired := CloudflareOriginPullConfig{CAs: cas, Clock: func() time.Time { return time.Now().Add(2 * time.Hour) }}
+	if code := send(expired, client); code != http.StatusForbidden {
+		t.Errorf("expired certificate: status = %d", code)
+	}
+}
+
+func TestCloudflareOriginPull_RequiresCAs(t *testing.T) {
+	for name, cas := range map[string]*x509.CertPool{"nil": nil, "empty": x509.NewCertPool()} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s CAs: CloudflareOriginPull should panic", name)
+				}
+			}()
+			CloudflareOriginPull(CloudflareOriginPullConfig{CAs: cas})
+		}()
+	}
+}
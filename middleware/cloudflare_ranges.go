@@ -0,0 +1,297 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/writdev-alt/portal-api-shared/logger"
+)
+
+const (
+	defaultCloudflareFetchTimeout = 10 * time.Second
+	maxCloudflareRangesBytes      = 1 << 20
+)
+
+var errNoCloudflareSource = errors.New("no Cloudflare IP range source configured")
+
+// CloudflareRangesConfig configures a CloudflareRanges provider
+type CloudflareRangesConfig struct {
+	// File is a JSON file ({"ipv4": [...], "ipv6": [...]}) or one CIDR per line
+	File string
+	// URL serves the same formats, or the Cloudflare API response of
+	// https://api.cloudflare.com/client/v4/ips. Used when File is empty.
+	URL string
+	// Interval reloads the ranges periodically once Start is called
+	Interval time.Duration
+	// Signals reload the ranges once Start is called, e.g. syscall.SIGHUP. Nothing
+	// is registered unless the application sets them.
+	Signals []os.Signal
+	// MinRanges rejects smaller sets, e.g. a truncated download. Zero only rejects
+	// empty sets.
+	MinRanges int
+	// HTTPClient fetches URL. Defaults to a client with a 10 second timeout.
+	HTTPClient *http.Client
+}
+
+// CloudflareRanges holds the current Cloudflare IP ranges. It starts with the
+// built-in ranges and swaps in reloaded sets only after validating them, so
+// lookups never see a partial or broken set.
+type CloudflareRanges struct {
+	config  CloudflareRangesConfig
+	current atomic.Pointer[[]*net.IPNet]
+}
+
+// NewCloudflareRanges creates a provider and loads the configured source. If the
+// source cannot be loaded the built-in ranges are kept and the error is logged.
+func NewCloudflareRanges(config CloudflareRangesConfig) *CloudflareRanges {
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: defaultCloudflareFetchTimeout}
+	}
+
+	p := &CloudflareRanges{config: config}
+	builtin := getCloudflareIPRanges()
+	p.current.Store(&builtin)
+
+	if config.File != "" || config.URL != "" {
+		if err := p.Reload(); err != nil {
+			logger.Errorf("Failed to load Cloudflare IP ranges, using built-in ranges: %v", err)
+		}
+	}
+	return p
+}
+
+// CloudflareRangesConfigFromEnv reads CLOUDFLARE_IPS_FILE, CLOUDFLARE_IPS_URL and
+// CLOUDFLARE_IPS_REFRESH_INTERVAL (e.g. "24h"). Signals are left for the caller to set.
+func CloudflareRangesConfigFromEnv() CloudflareRangesConfig {
+	config := CloudflareRangesConfig{
+		File: strings.TrimSpace(os.Getenv("CLOUDFLARE_IPS_FILE")),
+		URL:  strings.TrimSpace(os.Getenv("CLOUDFLARE_IPS_URL")),
+	}
+	if v := strings.TrimSpace(os.Getenv("CLOUDFLARE_IPS_REFRESH_INTERVAL")); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			config.Interval = d
+		}
+	}
+	return config
+}
+
+// Ranges returns the current ranges. The slice must not be modified.
+func (p *CloudflareRanges) Ranges() []*net.IPNet {
+	return *p.current.Load()
+}
+
+// Contains reports whether an IP belongs to Cloudflare
+func (p *CloudflareRanges) Contains(ip string) bool {
+	return isCloudflareIP(ip, p.Ranges())
+}
+
+// Reload loads the configured source and swaps in the new ranges if they are valid
+func (p *CloudflareRanges) Reload() error {
+	var (
+		data   []byte
+		source string
+		err    error
+	)
+	switch {
+	case p.config.File != "":
+		source = p.config.File
+		data, err = os.ReadFile(p.config.File)
+	case p.config.URL != "":
+		source = p.config.URL
+		data, err = p.fetch()
+	default:
+		return errNoCloudflareSource
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", source, err)
+	}
+
+	ranges, err := parseCloudflareRanges(data)
+	if err != nil {
+		return fmt.Errorf("invalid ranges from %s: %w", source, err)
+	}
+	if err := validateCloudflareRanges(ranges, p.config.MinRanges); err != nil {
+		return fmt.Errorf("invalid ranges from %s: %w", source, err)
+	}
+
+	added, removed := diffRanges(p.Ranges(), ranges)
+	p.current.Store(&ranges)
+	if len(added) > 0 || len(removed) > 0 {
+		logger.Infof("Cloudflare IP ranges updated from %s: %d ranges, added %v, removed %v", source, len(ranges), added, removed)
+	}
+	return nil
+}
+
+// Start reloads the ranges on the configured interval and signals until ctx is
+// done. It blocks, so run it in a goroutine owned by the application. Failed
+// reloads are logged and keep the current ranges.
+func (p *CloudflareRanges) Start(ctx context.Context) {
+	if p.config.Interval <= 0 && len(p.config.Signals) == 0 {
+		return
+	}
+
+	var tick <-chan time.Time
+	if p.config.Interval > 0 {
+		ticker := time.NewTicker(p.config.Interval)
+		tick = ticker.C
+		defer ticker.Stop()
+	}
+	signals := make(chan os.Signal, 1)
+	if len(p.config.Signals) > 0 {
+		signal.Notify(signals, p.config.Signals...)
+		defer signal.Stop(signals)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick:
+		case <-signals:
+		}
+		if err := p.Reload(); err != nil {
+			logger.Errorf("Failed to reload Cloudflare IP ranges, keeping current ranges: %v", err)
+		}
+	}
+}
+
+// fetch downloads the configured URL
+func (p *CloudflareRanges) fetch() ([]byte, error) {
+	resp, err := p.config.HTTPClient.Get(p.config.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxCloudflareRangesBytes))
+}
+
+// parseCloudflareRanges parses JSON ({"ipv4", "ipv6"} or the Cloudflare API response)
+// or one CIDR per line. Any invalid CIDR rejects the whole set.
+func parseCloudflareRanges(data []byte) ([]*net.IPNet, error) {
+	var cidrs []string
+	if trimmed := strings.TrimSpace(string(data)); strings.HasPrefix(trimmed, "{") {
+		var doc struct {
+			IPv4   []string `json:"ipv4"`
+			IPv6   []string `json:"ipv6"`
+			Result struct {
+				IPv4 []string `json:"ipv4_cidrs"`
+				IPv6 []string `json:"ipv6_cidrs"`
+			} `json:"result"`
+		}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		cidrs = slices.Concat(doc.IPv4, doc.IPv6, doc.Result.IPv4, doc.Result.IPv6)
+	} else {
+		for _, line := range strings.Split(trimmed, "\n") {
+			if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+				cidrs = append(cidrs, line)
+			}
+		}
+	}
+
+	ranges := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, network)
+	}
+	return ranges, nil
+}
+
+// validateCloudflareRanges rejects sets that are too small or contain ranges so
+// broad that trusting them would trust most of the internet
+func validateCloudflareRanges(ranges []*net.IPNet, minRanges int) error {
+	if len(ranges) == 0 {
+		return errors.New("no ranges")
+	}
+	if len(ranges) < minRanges {
+		return fmt.Errorf("got %d ranges, expected at least %d", len(ranges), minRanges)
+	}
+	for _, network := range ranges {
+		ones, bits := network.Mask.Size()
+		if (bits == 32 && ones < 8) || (bits == 128 && ones < 16) {
+			return fmt.Errorf("range %s is too broad", network)
+		}
+	}
+	return nil
+}
+
+// diffRanges returns the ranges added and removed between two sets
+func diffRanges(old, new []*net.IPNet) (added, removed []string) {
+	oldSet := make(map[string]bool, len(old))
+	for _, network := range old {
+		oldSet[network.String()] = true
+	}
+	newSet := make(map[string]bool, len(new))
+	for _, network := range new {
+		newSet[network.String()] = true
+		if !oldSet[network.String()] {
+			added = append(added, network.String())
+		}
+	}
+	for _, network := range old {
+		if !newSet[network.String()] {
+			removed = append(removed, network.String())
+		}
+	}
+	return added, removed
+}
+
+var (
+	cloudflareMu     sync.RWMutex
+	cloudflareRanges *CloudflareRanges
+)
+
+// DefaultCloudflareRanges returns the provider used by the middleware. On first use
+// it is built from the built-in ranges and CLOUDFLARE_IPS_FILE only: it runs in the
+// request path, so it never downloads CLOUDFLARE_IPS_URL or starts background work.
+// To use the URL or refresh the ranges, build a provider at startup, install it with
+// SetCloudflareRanges and run its Start with the application's context.
+func DefaultCloudflareRanges() *CloudflareRanges {
+	cloudflareMu.RLock()
+	p := cloudflareRanges
+	cloudflareMu.RUnlock()
+	if p != nil {
+		return p
+	}
+
+	cloudflareMu.Lock()
+	defer cloudflareMu.Unlock()
+	if cloudflareRanges == nil {
+		config := CloudflareRangesConfigFromEnv()
+		config.URL = ""
+		cloudflareRanges = NewCloudflareRanges(config)
+	}
+	return cloudflareRanges
+}
+
+// SetCloudflareRanges replaces the provider used by the middleware. Passing nil
+// makes the next call rebuild the lazy default.
+func SetCloudflareRanges(p *CloudflareRanges) {
+	cloudflareMu.Lock()
+	defer cloudflareMu.Unlock()
+	cloudflareRanges = p
+}
+
+// cloudflareIPRanges returns the current Cloudflare IP ranges
+func cloudflareIPRanges() []*net.IPNet {
+	return DefaultCloudflareRanges().Ranges()
+}
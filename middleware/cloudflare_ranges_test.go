@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCloudflareRanges_Reload(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "cloudflare-ips.json")
+	if err := os.WriteFile(file, []byte(`{"ipv4": ["198.51.100.0/24"], "ipv6": ["2001:db8::/32"]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewCloudflareRanges(CloudflareRangesConfig{File: file, MinRanges: 2})
+	if !p.Contains("198.51.100.7") || p.Contains("173.245.48.1") {
+		t.Fatalf("ranges from file not loaded: %v", p.Ranges())
+	}
+
+	// Invalid sets are rejected and the current ranges kept
+	for _, content := range []string{
+		``,
+		`{"ipv4": ["198.51.100.0/24"]}`,
+		`{"ipv4": ["198.51.100.0/24", "not-a-cidr"]}`,
+		`{"ipv4": ["198.51.100.0/24", "0.0.0.0/0"]}`,
+	} {
+		if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := p.Reload(); err == nil {
+			t.Errorf("Reload accepted %s", content)
+		}
+		if !p.Contains("198.51.100.7") {
+			t.Errorf("ranges replaced by rejected set %s", content)
+		}
+	}
+}
+
+func TestCloudflareRanges_URL(t *testing.T) {
+	body := `{"result": {"ipv4_cidrs": ["203.0.113.0/24"], "ipv6_cidrs": ["2001:db8::/32"]}, "success": true}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	p := NewCloudflareRanges(CloudflareRangesConfig{URL: server.URL, MinRanges: 2})
+	if !p.Contains("203.0.113.7") {
+		t.Fatalf("ranges from URL not loaded: %v", p.Ranges())
+	}
+
+	body = "# Cloudflare\n198.51.100.0/24\n2001:db8::/32\n"
+	if err := p.Reload(); err != nil {
+		t.Fatalf("Reload returned error: %v", err)
+	}
+	if !p.Contains("198.51.100.7") || p.Contains("203.0.113.7") {
+		t.Errorf("plain text ranges not swapped in: %v", p.Ranges())
+	}
+
+	// A failing source keeps the built-in ranges
+	server.Close()
+	fallback := NewCloudflareRanges(CloudflareRangesConfig{URL: server.URL})
+	if !fallback.Contains("173.245.48.1") {
+		t.Error("expected the built-in ranges when the source fails")
+	}
+}
+
+func TestCloudflareRanges_SmallFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "cloudflare-ips.txt")
+	if err := os.WriteFile(file, []byte("198.51.100.0/24\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without MinRanges any non-empty valid set is accepted
+	if p := NewCloudflareRanges(CloudflareRangesConfig{File: file}); !p.Contains("198.51.100.7") {
+		t.Errorf("single range file not loaded: %v", p.Ranges())
+	}
+}
+
+func TestDefaultCloudflareRanges_SkipsURL(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte("203.0.113.0/24\n"))
+	}))
+	defer server.Close()
+
+	file := filepath.Join(t.TempDir(), "cloudflare-ips.txt")
+	if err := os.WriteFile(file, []byte("198.51.100.0/24\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CLOUDFLARE_IPS_URL", server.URL)
+	t.Setenv("CLOUDFLARE_IPS_FILE", file)
+	SetCloudflareRanges(nil)
+	t.Cleanup(func() { SetCloudflareRanges(nil) })
+
+	// The lazy default runs in the request path, so it only reads the file
+	p := DefaultCloudflareRanges()
+	if !p.Contains("198.51.100.7") || requests != 0 {
+		t.Errorf("ranges = %v, URL requests = %d, expected the file only", p.Ranges(), requests)
+	}
+}
+
+func TestCloudflareRangesConfigFromEnv(t *testing.T) {
+	t.Setenv("CLOUDFLARE_IPS_FILE", "/etc/cloudflare/ips.txt")
+	t.Setenv("CLOUDFLARE_IPS_REFRESH_INTERVAL", "24h")
+
+	config := CloudflareRangesConfigFromEnv()
+	if config.File != "/etc/cloudflare/ips.txt" || config.Interval != 24*time.Hour {
+		t.Errorf("unexpected config: %+v", config)
+	}
+	// Signal handling is the application's decision
+	if len(config.Signals) != 0 {
+		t.Errorf("Signals = %v, expected none", config.Signals)
+	}
+}
@@ -0,0 +1,170 @@
+package middleware
+
+import (
+	"slices"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/writdev-alt/portal-api-shared/apikey"
+	sharedjwt "github.com/writdev-alt/portal-api-shared/jwt"
+)
+
+// Context keys set by AuthMiddleware
+const (
+	ContextKeyClaims = "claims"
+	// ContextKeyCustomClaims holds the *jwt.CustomClaims[T] validated by AuthCustom[T]
+	ContextKeyCustomClaims = "custom_claims"
+	ContextKeyUserID       = "user_id"
+	ContextKeyEmail        = "email"
+	ContextKeyName         = "name"
+	ContextKeyRoles        = "roles"
+	ContextKeyPermissions  = "permissions"
+	ContextKeyMerchantID   = "merchant_id"
+	ContextKeySessionID    = "session_id"
+	// ContextKeyRole holds the primary (first) role. It predates ContextKeyRoles
+	// and is kept for handlers that still read c.GetString("role").
+	ContextKeyRole = "role"
+)
+
+// Context keys set by APIKeyAuth, which also sets ContextKeyMerchantID
+const ContextKeyAPIKey = "api_key"
+
+// Context keys set by ServiceAuthMiddleware
+const (
+	ContextKeyServiceID     = "service_id"
+	ContextKeyServiceScopes = "service_scopes"
+)
+
+// setClaims stores access token claims and their typed fields in the context
+func setClaims[T any](c *gin.Context, claims *sharedjwt.CustomClaims[T]) {
+	c.Set(ContextKeyCustomClaims, claims)
+	c.Set(ContextKeyClaims, plainClaims(claims))
+	c.Set(ContextKeyUserID, claims.UUID)
+	c.Set(ContextKeyEmail, claims.Email)
+	c.Set(ContextKeyName, claims.Name)
+	c.Set(ContextKeyRoles, claims.Roles)
+	c.Set(ContextKeyRole, primaryRole(claims.Roles))
+	c.Set(ContextKeyPermissions, claims.Permissions)
+	c.Set(ContextKeyMerchantID, claims.MerchantID)
+	c.Set(ContextKeySessionID, claims.SessionID)
+}
+
+// plainClaims returns the claims without their service specific payload, so
+// CurrentClaims works whatever payload the token carries
+func plainClaims[T any](claims *sharedjwt.CustomClaims[T]) *sharedjwt.Claims {
+	if plain, ok := any(claims).(*sharedjwt.Claims); ok {
+		return plain
+	}
+	return &sharedjwt.Claims{
+		UUID:             claims.UUID,
+		Email:            claims.Email,
+		Name:             claims.Name,
+		Roles:            claims.Roles,
+		Permissions:      claims.Permissions,
+		MerchantID:       claims.MerchantID,
+		SessionID:        claims.SessionID,
+		TokenType:        claims.TokenType,
+		FamilyID:         claims.FamilyID,
+		IssuedAtNano:     claims.IssuedAtNano,
+		RegisteredClaims: claims.RegisteredClaims,
+	}
+}
+
+// CurrentClaims returns the claims of the authenticated request
+func CurrentClaims(c *gin.Context) (*sharedjwt.Claims, bool) {
+	v, exists := c.Get(ContextKeyClaims)
+	if !exists {
+		return nil, false
+	}
+	claims, ok := v.(*sharedjwt.Claims)
+	return claims, ok
+}
+
+// CurrentCustomClaims returns the typed claims stored by AuthCustom[T]. It reports
+// false when the request was authenticated with a different payload type.
+func CurrentCustomClaims[T any](c *gin.Context) (*sharedjwt.CustomClaims[T], bool) {
+	v, exists := c.Get(ContextKeyCustomClaims)
+	if !exists {
+		return nil, false
+	}
+	claims, ok := v.(*sharedjwt.CustomClaims[T])
+	return claims, ok
+}
+
+// CurrentAPIKey returns the API key that authenticated the request
+func CurrentAPIKey(c *gin.Context) (*apikey.APIKey, bool) {
+	v, exists := c.Get(ContextKeyAPIKey)
+	if !exists {
+		return nil, false
+	}
+	key, ok := v.(*apikey.APIKey)
+	return key, ok
+}
+
+// GetUserID returns the authenticated user's ID
+func GetUserID(c *gin.Context) string {
+	return c.GetString(ContextKeyUserID)
+}
+
+// GetEmail returns the authenticated user's email
+func GetEmail(c *gin.Context) string {
+	return c.GetString(ContextKeyEmail)
+}
+
+// GetName returns the authenticated user's name
+func GetName(c *gin.Context) string {
+	return c.GetString(ContextKeyName)
+}
+
+// GetRoles returns the authenticated user's roles
+func GetRoles(c *gin.Context) []string {
+	return c.GetStringSlice(ContextKeyRoles)
+}
+
+// primaryRole returns the first role, or an empty string when there is none
+func primaryRole(roles []string) string {
+	if len(roles) == 0 {
+		return ""
+	}
+	return roles[0]
+}
+
+// GetPermissions returns the authenticated user's permissions
+func GetPermissions(c *gin.Context) []string {
+	return c.GetStringSlice(ContextKeyPermissions)
+}
+
+// GetMerchantID returns the merchant the token is scoped to, if any
+func GetMerchantID(c *gin.Context) string {
+	return c.GetString(ContextKeyMerchantID)
+}
+
+// GetSessionID returns the session the token belongs to, if any
+func GetSessionID(c *gin.Context) string {
+	return c.GetString(ContextKeySessionID)
+}
+
+// HasRole reports whether the authenticated user has a role
+func HasRole(c *gin.Context, role string) bool {
+	return slices.Contains(GetRoles(c), role)
+}
+
+// HasPermission reports whether the authenticated user has a permission
+func HasPermission(c *gin.Context, permission string) bool {
+	return slices.Contains(GetPermissions(c), permission)
+}
+
+// GetServiceID returns the client ID of the calling service
+func GetServiceID(c *gin.Context) string {
+	return c.GetString(ContextKeyServiceID)
+}
+
+// GetServiceScopes returns the scopes granted to the calling service
+func GetServiceScopes(c *gin.Context) []string {
+	return c.GetStringSlice(ContextKeyServiceScopes)
+}
+
+// HasServiceScope reports whether the calling service was granted a scope
+func HasServiceScope(c *gin.Context, scope string) bool {
+	return slices.Contains(GetServiceScopes(c), scope)
+}
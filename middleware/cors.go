@@ -0,0 +1,218 @@
+package middleware
+
+import (
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/writdev-alt/portal-api-shared/logger"
+)
+
+// CORSConfig configures CORSWithConfig
+type CORSConfig struct {
+	// AllowOrigins lists exact origins ("https://admin.example.com"), wildcard
+	// subdomains ("https://*.example.com") or "*" for any origin
+	AllowOrigins []string
+	// AllowOriginPatterns lists regular expressions matched against the whole origin
+	AllowOriginPatterns []string
+	AllowMethods        []string
+	AllowHeaders        []string
+	// ExposeHeaders lists response headers readable by browser scripts
+	ExposeHeaders []string
+	// AllowCredentials allows cookies and Authorization headers. It is ignored
+	// when AllowOrigins contains "*", so credentials are only ever shared with
+	// origins that are listed or matched explicitly.
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache preflight responses
+	MaxAge time.Duration
+	// Routes overrides the configuration for path prefixes, e.g. a public
+	// "/api/v1/webhooks" next to a credentialed "/api/v1/admin". Prefixes match
+	// whole path segments and the longest matching prefix wins.
+	Routes map[string]CORSConfig
+}
+
+// DefaultCORSConfig allows any origin without credentials
+func DefaultCORSConfig() CORSConfig {
+	return CORSConfig{
+		AllowOrigins: []string{"*"},
+		AllowMethods: []string{"POST", "OPTIONS", "GET", "PUT", "DELETE", "PATCH"},
+		AllowHeaders: []string{"Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token", "Authorization", "accept", "origin", "Cache-Control", "X-Requested-With"},
+	}
+}
+
+// CORS middleware allowing any origin without credentials
+func CORS() gin.HandlerFunc {
+	return CORSWithConfig(DefaultCORSConfig())
+}
+
+// corsPolicy is a compiled CORSConfig
+type corsPolicy struct {
+	anyOrigin        bool
+	origins          []string
+	wildcards        [][2]string // scheme and host prefix, domain suffix
+	patterns         []*regexp.Regexp
+	allowMethods     string
+	allowHeaders     string
+	exposeHeaders    string
+	allowCredentials bool
+	maxAge           string
+}
+
+// corsRoute is a per-prefix override
+type corsRoute struct {
+	prefix string
+	policy *corsPolicy
+}
+
+// CORSWithConfig answers preflight requests and sets CORS headers for allowed
+// origins. Responses always vary by Origin, so caches never serve one origin's
+// headers to another.
+func CORSWithConfig(config CORSConfig) gin.HandlerFunc {
+	defaultPolicy := newCORSPolicy(config)
+
+	routes := make([]corsRoute, 0, len(config.Routes))
+	for prefix, routeConfig := range config.Routes {
+		routes = append(routes, corsRoute{prefix: prefix, policy: newCORSPolicy(routeConfig)})
+	}
+	// Longest prefix first
+	slices.SortFunc(routes, func(a, b corsRoute) int { return len(b.prefix) - len(a.prefix) })
+
+	return func(c *gin.Context) {
+		policy := defaultPolicy
+		for _, route := range routes {
+			if corsPathHasPrefix(c.Request.URL.Path, route.prefix) {
+				policy = route.policy
+				break
+			}
+		}
+
+		header := c.Writer.Header()
+		header.Add("Vary", "Origin")
+
+		origin := c.GetHeader("Origin")
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+		if preflight {
+			header.Add("Vary", "Access-Control-Request-Method")
+			header.Add("Vary", "Access-Control-Request-Headers")
+		}
+
+		if origin == "" {
+			c.Next()
+			return
+		}
+		if !policy.allowOrigin(origin) {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if policy.anyOrigin {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+			if policy.allowCredentials {
+				header.Set("Access-Control-Allow-Credentials", "true")
+			}
+		}
+
+		if preflight {
+			if policy.allowMethods != "" {
+				header.Set("Access-Control-Allow-Methods", policy.allowMethods)
+			}
+			if policy.allowHeaders != "" {
+				header.Set("Access-Control-Allow-Headers", policy.allowHeaders)
+			}
+			if policy.maxAge != "" {
+				header.Set("Access-Control-Max-Age", policy.maxAge)
+			}
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		if policy.exposeHeaders != "" {
+			header.Set("Access-Control-Expose-Headers", policy.exposeHeaders)
+		}
+		c.Next()
+	}
+}
+
+// newCORSPolicy compiles a configuration. Invalid origin patterns are logged and skipped.
+func newCORSPolicy(config CORSConfig) *corsPolicy {
+	p := &corsPolicy{
+		allowMethods:     strings.Join(config.AllowMethods, ", "),
+		allowHeaders:     strings.Join(config.AllowHeaders, ", "),
+		exposeHeaders:    strings.Join(config.ExposeHeaders, ", "),
+		allowCredentials: config.AllowCredentials,
+	}
+	if config.MaxAge > 0 {
+		p.maxAge = strconv.Itoa(int(config.MaxAge.Seconds()))
+	}
+
+	for _, origin := range config.AllowOrigins {
+		origin = strings.ToLower(strings.TrimSpace(origin))
+		switch {
+		case origin == "*":
+			p.anyOrigin = true
+		case strings.Contains(origin, "*"):
+			prefix, suffix, _ := strings.Cut(origin, "*")
+			p.wildcards = append(p.wildcards, [2]string{prefix, suffix})
+		case origin != "":
+			p.origins = append(p.origins, origin)
+		}
+	}
+	for _, pattern := range config.AllowOriginPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			logger.Errorf("Invalid CORS origin pattern %q: %v", pattern, err)
+			continue
+		}
+		p.patterns = append(p.patterns, re)
+	}
+	if p.anyOrigin && p.allowCredentials {
+		logger.Warnf("CORS AllowCredentials is ignored because AllowOrigins contains \"*\"")
+		p.allowCredentials = false
+	}
+	return p
+}
+
+// corsPathHasPrefix reports whether path is prefix or below it, so "/api/v1/admin"
+// matches "/api/v1/admin/users" but not "/api/v1/administrator"
+func corsPathHasPrefix(path, prefix string) bool {
+	if path == prefix {
+		return true
+	}
+	return strings.HasPrefix(path, strings.TrimSuffix(prefix, "/")+"/")
+}
+
+// allowOrigin reports whether an origin matches the policy
+func (p *corsPolicy) allowOrigin(origin string) bool {
+	if p.anyOrigin {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if slices.Contains(p.origins, origin) {
+		return true
+	}
+	for _, w := range p.wildcards {
+		if len(origin) > len(w[0])+len(w[1]) && strings.HasPrefix(origin, w[0]) && strings.HasSuffix(origin, w[1]) {
+			// The wildcard only stands for subdomain labels
+			if sub := origin[len(w[0]) : len(origin)-len(w[1])]; !strings.ContainsAny(sub, "/:@") {
+				return true
+			}
+		}
+	}
+	for _, re := range p.patterns {
+		if loc := re.FindStringIndex(origin); loc != nil && loc[0] == 0 && loc[1] == len(origin) {
+			return true
+		}
+	}
+	return false
+}
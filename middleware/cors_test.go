@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCORSWithConfig(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(CORSWithConfig(CORSConfig{
+		AllowOrigins:        []string{"https://portal.example.com", "https://*.merchant.example.com"},
+		AllowOriginPatterns: []string{`https://preview-[0-9]+\.example\.dev`},
+		AllowMethods:        []string{"GET", "POST"},
+		AllowHeaders:        []string{"Authorization", "Content-Type"},
+		ExposeHeaders:       []string{"X-Request-ID"},
+		AllowCredentials:    true,
+		MaxAge:              10 * time.Minute,
+		Routes: map[string]CORSConfig{
+			"/public": {AllowOrigins: []string{"*"}, AllowMethods: []string{"GET"}},
+		},
+	}))
+	ok := func(c *gin.Context) { c.Status(http.StatusNoContent) }
+	router.GET("/", ok)
+	router.GET("/public/rates", ok)
+	router.GET("/publications", ok)
+
+	send := func(method, path, origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		if method == http.MethodOptions {
+			req.Header.Set("Access-Control-Request-Method", "POST")
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, origin := range []string{"https://portal.example.com", "https://shop.merchant.example.com", "https://preview-42.example.dev"} {
+		w := send(http.MethodGet, "/", origin)
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != origin {
+			t.Errorf("%s: Access-Control-Allow-Origin = %q", origin, got)
+		}
+		if w.Header().Get("Access-Control-Allow-Credentials") != "true" || w.Header().Get("Access-Control-Expose-Headers") != "X-Request-ID" {
+			t.Errorf("%s: headers = %v", origin, w.Header())
+		}
+	}
+
+	for _, origin := range []string{"https://evil.com", "https://merchant.example.com", "https://evil.com/.merchant.example.com", "https://preview-42.example.dev.evil.com"} {
+		w := send(http.MethodGet, "/", origin)
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+			t.Errorf("%s: Access-Control-Allow-Origin = %q, want none", origin, got)
+		}
+		if w.Header().Get("Vary") != "Origin" {
+			t.Errorf("%s: Vary = %q", origin, w.Header().Get("Vary"))
+		}
+	}
+
+	w := send(http.MethodOptions, "/", "https://portal.example.com")
+	if w.Code != http.StatusNoContent || w.Header().Get("Access-Control-Allow-Methods") != "GET, POST" || w.Header().Get("Access-Control-Max-Age") != "600" {
+		t.Errorf("preflight: status = %d, headers = %v", w.Code, w.Header())
+	}
+	if w := send(http.MethodOptions, "/", "https://evil.com"); w.Code != http.StatusForbidden {
+		t.Errorf("disallowed preflight: status = %d", w.Code)
+	}
+
+	// The route override allows any origin, so credentials are never sent with "*"
+	w = send(http.MethodGet, "/public/rates", "https://anyone.example.org")
+	if w.Header().Get("Access-Control-Allow-Origin") != "*" || w.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Errorf("public route: headers = %v", w.Header())
+	}
+
+	// Route prefixes match whole path segments only
+	w = send(http.MethodGet, "/publications", "https://anyone.example.org")
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("/publications: Access-Control-Allow-Origin = %q, want none", got)
+	}
+}
+
+func TestCORSWithConfig_AnyOriginWithCredentials(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(CORSWithConfig(CORSConfig{AllowOrigins: []string{"*"}, AllowCredentials: true}))
+	router.GET("/", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Origin", "https://evil.com")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Any origin may read public responses, but never with the user's credentials
+	if w.Header().Get("Access-Control-Allow-Origin") != "*" || w.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Errorf("headers = %v", w.Header())
+	}
+}
@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/writdev-alt/portal-api-shared/ipwhitelist"
+	"github.com/writdev-alt/portal-api-shared/logger"
+	response "github.com/writdev-alt/portal-api-shared/responses"
+)
+
+// Special CF-IPCountry values
+const (
+	CountryTor     = "T1" // Tor exit nodes
+	CountryUnknown = "XX" // Unknown, also used when the header cannot be trusted
+)
+
+// GeoRule overrides the country lists for Tor or unknown traffic
+type GeoRule int
+
+const (
+	GeoRuleDefault GeoRule = iota // Apply the country lists
+	GeoRuleAllow
+	GeoRuleDeny
+)
+
+// GeoPolicy configures GeoRestrict. Apply a different policy to each route group.
+type GeoPolicy struct {
+	// Name identifies the policy in audit logs
+	Name string
+	// AllowCountries, when set, only allows these ISO 3166-1 alpha-2 codes
+	AllowCountries []string
+	// DenyCountries rejects these codes, even when they are allowed
+	DenyCountries []string
+	// Tor and Unknown decide T1 and XX traffic separately from the lists
+	Tor     GeoRule
+	Unknown GeoRule
+	// BypassRoles lets authenticated users with any of these roles through, e.g.
+	// "admin". Requires Auth to run first.
+	BypassRoles []string
+	// BypassIPs lets these IPs and CIDRs through
+	BypassIPs []string
+}
+
+// GeoRestrict allows or denies requests by the CF-IPCountry header. The header is
+// only trusted on requests that came through Cloudflare; others count as unknown.
+// Blocked and bypassed requests are audit logged.
+func GeoRestrict(policy GeoPolicy) gin.HandlerFunc {
+	allow := upperAll(policy.AllowCountries)
+	deny := upperAll(policy.DenyCountries)
+	bypassIPs, err := ipwhitelist.NewTrie(policy.BypassIPs...)
+	if err != nil {
+		logger.Errorf("Invalid geo policy %q bypass IPs, bypass by IP disabled: %v", policy.Name, err)
+		bypassIPs = &ipwhitelist.Trie{}
+	}
+
+	return func(c *gin.Context) {
+		realIP, viaCloudflare := DefaultClientIPResolver().Resolve(c.Request)
+		country := CountryUnknown
+		if header := strings.ToUpper(strings.TrimSpace(GetCloudflareCountry(c))); viaCloudflare && header != "" {
+			country = header
+		}
+
+		if geoAllowed(country, policy, allow, deny) {
+			c.Next()
+			return
+		}
+
+		fields := logger.Fields{
+			"policy":      policy.Name,
+			"country":     country,
+			"ip":          realIP,
+			"method":      c.Request.Method,
+			"path":        c.Request.URL.Path,
+			"user_id":     GetUserID(c),
+			"merchant_id": GetMerchantID(c),
+			"cf_ray":      GetCloudflareRay(c),
+		}
+		if geoBypassed(c, policy, bypassIPs, realIP) {
+			logger.InfoContext(c.Request.Context(), "Geo policy bypassed", fields)
+			c.Next()
+			return
+		}
+
+		logger.WarnContext(c.Request.Context(), "Geo policy blocked request", fields)
+		response.Result(c, http.StatusForbidden, response.ServiceCodeCommon, response.CaseCodeOperationNotAllowed, nil, "Access from your location is not allowed")
+		c.Abort()
+	}
+}
+
+// geoAllowed applies the Tor and unknown rules, then the country lists
+func geoAllowed(country string, policy GeoPolicy, allow, deny []string) bool {
+	rule := GeoRuleDefault
+	switch country {
+	case CountryTor:
+		rule = policy.Tor
+	case CountryUnknown:
+		rule = policy.Unknown
+	}
+	if rule != GeoRuleDefault {
+		return rule == GeoRuleAllow
+	}
+
+	if slices.Contains(deny, country) {
+		return false
+	}
+	return len(allow) == 0 || slices.Contains(allow, country)
+}
+
+// geoBypassed reports whether a bypass rule lets the request through
+func geoBypassed(c *gin.Context, policy GeoPolicy, bypassIPs *ipwhitelist.Trie, realIP string) bool {
+	for _, role := range policy.BypassRoles {
+		if HasRole(c, role) {
+			return true
+		}
+	}
+	if addr, err := netip.ParseAddr(realIP); err == nil && bypassIPs.Contains(addr) {
+		return true
+	}
+	return false
+}
+
+func upperAll(values []string) []string {
+	upper := make([]string, len(values))
+	for i, v := range values {
+		upper[i] = strings.ToUpper(strings.TrimSpace(v))
+	}
+	return upper
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGeoRestrict(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetClientIPResolver(&ClientIPResolver{trustCloudflare: true})
+	t.Cleanup(func() { SetClientIPResolver(nil) })
+
+	router := gin.New()
+	router.GET("/", func(c *gin.Context) {
+		if role := c.Query("role"); role != "" {
+			c.Set(ContextKeyRoles, []string{role})
+		}
+	}, GeoRestrict(GeoPolicy{
+		Name:           "payments",
+		AllowCountries: []string{"ID", "sg", "T1"},
+		Unknown:        GeoRuleDeny,
+		Tor:            GeoRuleDeny,
+		BypassRoles:    []string{"admin"},
+		BypassIPs:      []string{"198.51.100.0/24"},
+	}), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	const cloudflare = "173.245.48.1:1234"
+	tests := []struct {
+		name       string
+		remoteAddr string
+		country    string
+		query      string
+		want       int
+	}{
+		{"allowed country", cloudflare, "ID", "", http.StatusNoContent},
+		{"lowercase list entry", cloudflare, "SG", "", http.StatusNoContent},
+		{"other country", cloudflare, "US", "", http.StatusForbidden},
+		{"Tor rule overrides the list", cloudflare, "T1", "", http.StatusForbidden},
+		{"unknown", cloudflare, "XX", "", http.StatusForbidden},
+		{"missing header", cloudflare, "", "", http.StatusForbidden},
+		{"header not from Cloudflare", "203.0.113.5:1234", "ID", "", http.StatusForbidden},
+		{"admin bypass", cloudflare, "US", "?role=admin", http.StatusNoContent},
+		{"other role", cloudflare, "US", "?role=merchant", http.StatusForbidden},
+		{"IP bypass", "198.51.100.7:1234", "US", "", http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/"+tt.query, nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.country != "" {
+				req.Header.Set("CF-IPCountry", tt.country)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
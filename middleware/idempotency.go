@@ -0,0 +1,277 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/writdev-alt/portal-api-shared/crypto"
+	"github.com/writdev-alt/portal-api-shared/logger"
+	"github.com/writdev-alt/portal-api-shared/redis"
+	response "github.com/writdev-alt/portal-api-shared/responses"
+)
+
+// IdempotencyKeyHeader carries the client chosen key of a mutation
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotentReplayedHeader is set on responses replayed from a previous request
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+const (
+	idempotencyPrefix         = "idempotency:"
+	defaultIdempotencyTTL     = 24 * time.Hour
+	defaultIdempotencyLockTTL = time.Minute
+	maxIdempotencyKeyLength   = 255
+)
+
+// IdempotencyConfig configures Idempotency
+type IdempotencyConfig struct {
+	// TTL is how long responses are kept for replay. Defaults to 24 hours.
+	TTL time.Duration
+	// LockTTL bounds how long a request holds its key; it must exceed the slowest
+	// handler. Defaults to 1 minute.
+	LockTTL time.Duration
+	// Required rejects mutations without an Idempotency-Key header
+	Required bool
+	// MaxBodyBytes limits the fingerprinted body size. Defaults to 10 MiB.
+	MaxBodyBytes int64
+	// Scope separates the keys of different callers. Defaults to the merchant,
+	// then the user, then the client IP.
+	Scope func(c *gin.Context) string
+}
+
+// idempotencyRecord is a completed response stored for replay
+type idempotencyRecord struct {
+	Fingerprint string `json:"fingerprint"`
+	Status      int    `json:"status"`
+	ContentType string `json:"contentType"`
+	// Header holds the headers set by the handler, e.g. Location
+	Header http.Header `json:"header,omitempty"`
+	Body   []byte      `json:"body"`
+}
+
+// idempotencyWriter captures the response written by the handler
+type idempotencyWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *idempotencyWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *idempotencyWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency makes POST, PUT, PATCH and DELETE handlers safe to retry. The first
+// request with an Idempotency-Key runs the handler while holding a lock in Redis;
+// its response is stored and replayed for retries with the same payload. A retry
+// while the first request is still running gets CaseCodeConflict, and reusing a key
+// for a different payload gets CaseCodeAlreadyProcessed. Server errors are not
+// stored, so they can be retried.
+func Idempotency(config IdempotencyConfig) gin.HandlerFunc {
+	if config.TTL <= 0 {
+		config.TTL = defaultIdempotencyTTL
+	}
+	if config.LockTTL <= 0 {
+		config.LockTTL = defaultIdempotencyLockTTL
+	}
+	if config.MaxBodyBytes <= 0 {
+		config.MaxBodyBytes = defaultMaxSignedBody
+	}
+	if config.Scope == nil {
+		config.Scope = defaultIdempotencyScope
+	}
+
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			c.Next()
+			return
+		}
+
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			if config.Required {
+				response.Result(c, http.StatusBadRequest, response.ServiceCodeCommon, response.CaseCodeRequiredField, nil, "Idempotency-Key header is required")
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			response.Result(c, http.StatusBadRequest, response.ServiceCodeCommon, response.CaseCodeInvalidValue, nil, "Idempotency-Key is too long")
+			c.Abort()
+			return
+		}
+		if !redis.IsEnabled() {
+			abortIdempotencyUnavailable(c)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, config.MaxBodyBytes))
+		if isBodyTooLarge(err) {
+			response.Result(c, http.StatusRequestEntityTooLarge, response.ServiceCodeCommon, response.CaseCodeInvalidValue, nil, "Request body too large")
+			c.Abort()
+			return
+		}
+		if err != nil {
+			response.Result(c, http.StatusBadRequest, response.ServiceCodeCommon, response.CaseCodeInvalidValue, nil, "Failed to read request body")
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		fingerprint := crypto.BodyHash([]byte(c.Request.Method + " " + c.Request.URL.RequestURI() + "\n" + string(body)))
+		recordKey := idempotencyPrefix + config.Scope(c) + ":" + key
+		lockKey := recordKey + ":lock"
+
+		if replayed, ok := replayIdempotent(c, recordKey, fingerprint); replayed || !ok {
+			return
+		}
+
+		// The lock holds the fingerprint, to tell retries from reused keys, and a
+		// token so only this request can release it
+		lockValue := fingerprint + ":" + uuid.NewString()
+		acquired, err := redis.AcquireLock(lockKey, lockValue, config.LockTTL)
+		if err != nil {
+			abortIdempotencyUnavailable(c)
+			return
+		}
+		if !acquired {
+			inFlight, err := redis.Get(lockKey)
+			if err != nil {
+				abortIdempotencyUnavailable(c)
+				return
+			}
+			if inFlight == "" {
+				// The lock was released just now, so the response has been stored
+				if replayed, ok := replayIdempotent(c, recordKey, fingerprint); replayed || !ok {
+					return
+				}
+			} else if inFlightFingerprint, _, _ := strings.Cut(inFlight, ":"); inFlightFingerprint != fingerprint {
+				abortIdempotencyMismatch(c)
+				return
+			}
+			response.Result(c, http.StatusConflict, response.ServiceCodeCommon, response.CaseCodeConflict, nil, "A request with this Idempotency-Key is still being processed")
+			c.Abort()
+			return
+		}
+		defer func() {
+			if _, err := redis.ReleaseOwnedLock(lockKey, lockValue); err != nil {
+				logger.WarnContext(c.Request.Context(), "Failed to release idempotency lock", logger.Fields{"key": lockKey, "error": err.Error()})
+			}
+		}()
+
+		// Another request may have completed between the first lookup and the lock
+		if replayed, ok := replayIdempotent(c, recordKey, fingerprint); replayed || !ok {
+			return
+		}
+
+		writer := &idempotencyWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		// Headers set before the handler, e.g. by RequestID, belong to this request only
+		before := writer.Header().Clone()
+		c.Next()
+
+		status := writer.Status()
+		if status >= http.StatusInternalServerError {
+			return
+		}
+		record, err := json.Marshal(idempotencyRecord{
+			Fingerprint: fingerprint,
+			Status:      status,
+			ContentType: writer.Header().Get("Content-Type"),
+			Header:      handlerHeaders(before, writer.Header()),
+			Body:        writer.body.Bytes(),
+		})
+		if err == nil {
+			err = redis.Set(recordKey, record, config.TTL)
+		}
+		if err != nil {
+			logger.ErrorContext(c.Request.Context(), "Failed to store idempotent response", logger.Fields{"key": recordKey, "error": err.Error()})
+		}
+	}
+}
+
+// replayIdempotent writes the stored response for a key, if any. It reports whether
+// a response was written and whether the request may proceed.
+func replayIdempotent(c *gin.Context, recordKey, fingerprint string) (replayed bool, ok bool) {
+	data, err := redis.Get(recordKey)
+	if err != nil {
+		abortIdempotencyUnavailable(c)
+		return false, false
+	}
+	if data == "" {
+		return false, true
+	}
+
+	var record idempotencyRecord
+	if err := json.Unmarshal([]byte(data), &record); err != nil {
+		abortIdempotencyUnavailable(c)
+		return false, false
+	}
+	if record.Fingerprint != fingerprint {
+		abortIdempotencyMismatch(c)
+		return false, false
+	}
+
+	for name, values := range record.Header {
+		c.Writer.Header()[name] = values
+	}
+	c.Header(IdempotentReplayedHeader, "true")
+	c.Data(record.Status, record.ContentType, record.Body)
+	c.Abort()
+	return true, true
+}
+
+// handlerHeaders returns the headers the handler added or changed, leaving out
+// the ones replay sets itself
+func handlerHeaders(before, after http.Header) http.Header {
+	header := http.Header{}
+	for name, values := range after {
+		switch name {
+		case "Content-Type", "Content-Length", IdempotentReplayedHeader:
+			continue
+		}
+		if !slices.Equal(before[name], values) {
+			header[name] = values
+		}
+	}
+	return header
+}
+
+// abortIdempotencyMismatch rejects a key reused for a different request
+func abortIdempotencyMismatch(c *gin.Context) {
+	response.Result(c, http.StatusUnprocessableEntity, response.ServiceCodeCommon, response.CaseCodeAlreadyProcessed, nil, "Idempotency-Key has already been used for a different request")
+	c.Abort()
+}
+
+// abortIdempotencyUnavailable rejects a mutation that cannot be protected against double execution
+func abortIdempotencyUnavailable(c *gin.Context) {
+	response.Result(c, http.StatusServiceUnavailable, response.ServiceCodeCommon, response.CaseCodeServiceUnavailable, nil, "Idempotency check is unavailable")
+	c.Abort()
+}
+
+// defaultIdempotencyScope scopes keys to the merchant, then the user, then the client IP
+func defaultIdempotencyScope(c *gin.Context) string {
+	if merchantID := GetMerchantID(c); merchantID != "" {
+		return "merchant:" + merchantID
+	}
+	if userID := GetUserID(c); userID != "" {
+		return "user:" + userID
+	}
+	return "ip:" + ClientIP(c)
+}
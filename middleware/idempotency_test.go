@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/writdev-alt/portal-api-shared/crypto"
+	"github.com/writdev-alt/portal-api-shared/redis"
+	response "github.com/writdev-alt/portal-api-shared/responses"
+)
+
+func TestIdempotency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupRedisTest(t)
+
+	calls := 0
+	router := gin.New()
+	router.POST("/withdrawals", Idempotency(IdempotencyConfig{Required: true}), func(c *gin.Context) {
+		calls++
+		c.Header("Location", "/withdrawals/1")
+		response.Created(c, response.ServiceCodeWithdrawal, gin.H{"call": calls}, "")
+	})
+
+	send := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/withdrawals", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		req.RemoteAddr = "203.0.113.1:1234"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	first := send("key-1", `{"amount":1000}`)
+	if first.Code != http.StatusCreated || calls != 1 {
+		t.Fatalf("first request: status = %d, calls = %d", first.Code, calls)
+	}
+
+	retry := send("key-1", `{"amount":1000}`)
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() || calls != 1 {
+		t.Errorf("retry: status = %d, body = %s, calls = %d", retry.Code, retry.Body.String(), calls)
+	}
+	if retry.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Error("expected the retry to be marked as replayed")
+	}
+	if retry.Header().Get("Location") != "/withdrawals/1" {
+		t.Errorf("Location = %q, expected the stored header to be replayed", retry.Header().Get("Location"))
+	}
+
+	w := send("key-1", `{"amount":2000}`)
+	if w.Code != http.StatusUnprocessableEntity || responseCaseCode(t, w) != response.CaseCodeAlreadyProcessed {
+		t.Errorf("different payload: status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	if w := send("", `{"amount":1000}`); w.Code != http.StatusBadRequest {
+		t.Errorf("missing key: status = %d, expected %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestIdempotency_BodyTooLarge(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupRedisTest(t)
+
+	calls := 0
+	router := gin.New()
+	router.POST("/withdrawals", Idempotency(IdempotencyConfig{MaxBodyBytes: 16}), func(c *gin.Context) {
+		calls++
+		c.Status(http.StatusNoContent)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/withdrawals", strings.NewReader(`{"amount":1000000}`))
+	req.Header.Set(IdempotencyKeyHeader, "key-1")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge || calls != 0 {
+		t.Errorf("status = %d, calls = %d, expected %d without calling the handler", w.Code, calls, http.StatusRequestEntityTooLarge)
+	}
+}
+
+func TestIdempotency_InFlight(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupRedisTest(t)
+
+	router := gin.New()
+	router.POST("/deposits", Idempotency(IdempotencyConfig{}), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	// Simulate the same request still holding the key
+	scope := "ip:203.0.113.1"
+	fingerprint := crypto.BodyHash([]byte("POST /deposits\n{}"))
+	if _, err := redis.AcquireLock(idempotencyPrefix+scope+":key-1:lock", fingerprint+":other-request", defaultIdempotencyLockTTL); err != nil {
+		t.Fatalf("AcquireLock returned error: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/deposits", strings.NewReader(`{}`))
+	req.Header.Set(IdempotencyKeyHeader, "key-1")
+	req.RemoteAddr = "203.0.113.1:1234"
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusConflict || responseCaseCode(t, w) != response.CaseCodeConflict {
+		t.Errorf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+}
+
+func TestIdempotency_ReleasesOnlyOwnLock(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupRedisTest(t)
+
+	lockKey := idempotencyPrefix + "ip:203.0.113.1:key-1:lock"
+	router := gin.New()
+	router.POST("/deposits", Idempotency(IdempotencyConfig{}), func(c *gin.Context) {
+		// Our lock expired and another request took the key meanwhile
+		_ = redis.Set(lockKey, "other-request", defaultIdempotencyLockTTL)
+		c.Status(http.StatusNoContent)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/deposits", strings.NewReader(`{}`))
+	req.Header.Set(IdempotencyKeyHeader, "key-1")
+	req.RemoteAddr = "203.0.113.1:1234"
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	if value, _ := redis.Get(lockKey); value != "other-request" {
+		t.Errorf("lock = %q, expected the other request's lock to be kept", value)
+	}
+}
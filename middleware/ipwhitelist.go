@@ -3,7 +3,6 @@ package middleware
 import (
 	"net"
 	"net/http"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,6 +12,8 @@ type IPWhitelistConfig struct {
 	AllowedIPs     []string
 	AllowedCIDRs   []string
 	CloudflareOnly bool
+	// Deprecated: the client IP comes from DefaultClientIPResolver and this field
+	// is ignored. Set ClientIPConfig.TrustCloudflare (TRUST_CLOUDFLARE) instead.
 	TrustCloudflare bool
 }
 
@@ -29,16 +30,13 @@ func IPWhitelist(config IPWhitelistConfig) gin.HandlerFunc {
 		}
 	}
 
-	// Cloudflare IP ranges
-	cloudflareIPs := getCloudflareIPRanges()
-
 	return func(c *gin.Context) {
-		// Get real IP (prioritize Cloudflare header)
-		realIP := getRealIP(c)
+		// Forwarding headers are only honoured from trusted proxies
+		realIP, viaCloudflare := DefaultClientIPResolver().Resolve(c.Request)
 
-		// If Cloudflare only mode, check if IP is from Cloudflare
+		// If Cloudflare only mode, check the request came through Cloudflare
 		if config.CloudflareOnly {
-			if !isCloudflareIP(realIP, cloudflareIPs) {
+			if !viaCloudflare {
 				c.JSON(http.StatusForbidden, gin.H{
 					"error": "Access denied: Request must come from Cloudflare",
 				})
@@ -62,30 +60,6 @@ func IPWhitelist(config IPWhitelistConfig) gin.HandlerFunc {
 	}
 }
 
-// getRealIP extracts the real client IP from request
-func getRealIP(c *gin.Context) string {
-	// Cloudflare header (most reliable)
-	if cfIP := c.GetHeader("CF-Connecting-IP"); cfIP != "" {
-		return cfIP
-	}
-
-	// X-Forwarded-For header
-	if xff := c.GetHeader("X-Forwarded-For"); xff != "" {
-		ips := strings.Split(xff, ",")
-		if len(ips) > 0 {
-			return strings.TrimSpace(ips[0])
-		}
-	}
-
-	// X-Real-IP header
-	if xri := c.GetHeader("X-Real-IP"); xri != "" {
-		return xri
-	}
-
-	// Fallback to RemoteAddr
-	return c.ClientIP()
-}
-
 // isIPAllowed checks if IP is in the whitelist
 func isIPAllowed(ip string, allowedIPs []string, allowedNetworks []*net.IPNet) bool {
 	parsedIP := net.ParseIP(ip)
@@ -126,12 +100,8 @@ func isCloudflareIP(ip string, cloudflareRanges []*net.IPNet) bool {
 	return false
 }
 
-// getCloudflareIPRangesInternal returns Cloudflare IP ranges (internal, used by cloudflare.go)
-func getCloudflareIPRangesInternal() []*net.IPNet {
-	return getCloudflareIPRanges()
-}
-
-// getCloudflareIPRanges returns Cloudflare IP ranges (internal helper)
+// getCloudflareIPRanges returns the built-in Cloudflare IP ranges, used until
+// CloudflareRanges loads a newer set
 func getCloudflareIPRanges() []*net.IPNet {
 	var ranges []*net.IPNet
 
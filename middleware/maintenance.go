@@ -0,0 +1,251 @@
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/writdev-alt/portal-api-shared/ipwhitelist"
+	"github.com/writdev-alt/portal-api-shared/logger"
+	"github.com/writdev-alt/portal-api-shared/redis"
+	response "github.com/writdev-alt/portal-api-shared/responses"
+)
+
+// MaintenanceBypassHeader carries a token that bypasses maintenance mode
+const MaintenanceBypassHeader = "X-Maintenance-Bypass"
+
+const (
+	defaultMaintenanceRetryAfter      = 5 * time.Minute
+	defaultMaintenanceRefreshInterval = 5 * time.Second
+	defaultMaintenanceMessage         = "Service is under maintenance"
+	// maintenanceGracePeriod keeps ended windows readable briefly so late reads
+	// still see the end time
+	maintenanceGracePeriod = time.Minute
+)
+
+var (
+	ErrMaintenanceRedisDisabled = errors.New("redis is not configured")
+	ErrInvalidMaintenanceWindow = errors.New("maintenance window ends before it starts")
+)
+
+// MaintenanceWindow is a scheduled or ongoing maintenance, either global or for
+// one service code
+type MaintenanceWindow struct {
+	ServiceCode string    `json:"serviceCode,omitempty"` // Empty for every service
+	Message     string    `json:"message,omitempty"`
+	StartsAt    time.Time `json:"startsAt"`
+	EndsAt      time.Time `json:"endsAt,omitzero"` // Zero until disabled
+}
+
+// Active reports whether the window covers t
+func (w *MaintenanceWindow) Active(t time.Time) bool {
+	return !t.Before(w.StartsAt) && (w.EndsAt.IsZero() || t.Before(w.EndsAt))
+}
+
+// ScheduleMaintenance stores a window in Redis, replacing the previous window of
+// the same scope. A zero StartsAt starts it now.
+func ScheduleMaintenance(window MaintenanceWindow) error {
+	if !redis.IsEnabled() {
+		return ErrMaintenanceRedisDisabled
+	}
+	if window.StartsAt.IsZero() {
+		window.StartsAt = time.Now()
+	}
+	var ttl time.Duration
+	if !window.EndsAt.IsZero() {
+		if !window.EndsAt.After(window.StartsAt) {
+			return ErrInvalidMaintenanceWindow
+		}
+		ttl = time.Until(window.EndsAt) + maintenanceGracePeriod
+	}
+
+	data, err := json.Marshal(window)
+	if err != nil {
+		return err
+	}
+	return redis.SetMaintenance(maintenanceScope(window.ServiceCode), data, ttl)
+}
+
+// EnableMaintenance starts maintenance now until DisableMaintenance is called.
+// An empty service code puts every service in maintenance.
+func EnableMaintenance(serviceCode, message string) error {
+	return ScheduleMaintenance(MaintenanceWindow{ServiceCode: serviceCode, Message: message})
+}
+
+// DisableMaintenance ends or cancels the window of a service code, or the global one
+func DisableMaintenance(serviceCode string) error {
+	if !redis.IsEnabled() {
+		return ErrMaintenanceRedisDisabled
+	}
+	return redis.DeleteMaintenance(maintenanceScope(serviceCode))
+}
+
+// ActiveMaintenance returns the window covering t for a service code, checking the
+// global window first, or nil when the service is available
+func ActiveMaintenance(serviceCode string, t time.Time) (*MaintenanceWindow, error) {
+	if !redis.IsEnabled() {
+		return nil, nil
+	}
+	scopes := []string{maintenanceScope("")}
+	if serviceCode != "" {
+		scopes = append(scopes, maintenanceScope(serviceCode))
+	}
+	values, err := redis.GetMaintenance(scopes...)
+	if err != nil {
+		return nil, err
+	}
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+		var window MaintenanceWindow
+		if err := json.Unmarshal([]byte(value), &window); err != nil {
+			return nil, err
+		}
+		if window.Active(t) {
+			return &window, nil
+		}
+	}
+	return nil, nil
+}
+
+// MaintenanceConfig configures the Maintenance middleware
+type MaintenanceConfig struct {
+	// ServiceCode selects the per-service flag and the service code of the response
+	ServiceCode string
+	// BypassIPs lets these IPs and CIDRs through, e.g. the office network
+	BypassIPs []string
+	// BypassTokens are accepted in the X-Maintenance-Bypass header
+	BypassTokens []string
+	// BypassRoles lets authenticated users with these roles through. Requires Auth to run first.
+	BypassRoles []string
+	// RetryAfter is sent for windows without an end. Defaults to 5 minutes.
+	RetryAfter time.Duration
+	// RefreshInterval is how long the flags are cached per instance. Defaults to 5 seconds.
+	RefreshInterval time.Duration
+	// Clock returns the current time. Defaults to time.Now.
+	Clock func() time.Time
+}
+
+// maintenanceState caches the windows read from Redis
+type maintenanceState struct {
+	mu        sync.Mutex
+	window    *MaintenanceWindow
+	checkedAt time.Time
+	// refreshing is set while one request reads Redis; the others keep using the
+	// cached window instead of piling onto Redis
+	refreshing bool
+}
+
+// Maintenance answers 503 with CaseCodeMaintenance and Retry-After while a global or
+// per-service maintenance window is active. The flags live in Redis so every
+// instance follows ScheduleMaintenance, EnableMaintenance and DisableMaintenance
+// without a redeploy. Without Redis, or when it fails, requests are served.
+func Maintenance(config MaintenanceConfig) gin.HandlerFunc {
+	if config.RetryAfter <= 0 {
+		config.RetryAfter = defaultMaintenanceRetryAfter
+	}
+	if config.RefreshInterval <= 0 {
+		config.RefreshInterval = defaultMaintenanceRefreshInterval
+	}
+	if config.Clock == nil {
+		config.Clock = time.Now
+	}
+	serviceCode := config.ServiceCode
+	if serviceCode == "" {
+		serviceCode = response.ServiceCodeCommon
+	}
+	bypassIPs, err := ipwhitelist.NewTrie(config.BypassIPs...)
+	if err != nil {
+		logger.Errorf("Invalid maintenance bypass IPs, bypass by IP disabled: %v", err)
+		bypassIPs = &ipwhitelist.Trie{}
+	}
+	state := &maintenanceState{}
+
+	return func(c *gin.Context) {
+		now := config.Clock()
+		window := state.current(c.Request.Context(), config, now)
+		if window == nil || !window.Active(now) || maintenanceBypassed(c, config, bypassIPs) {
+			c.Next()
+			return
+		}
+
+		retryAfter := config.RetryAfter
+		if !window.EndsAt.IsZero() {
+			retryAfter = window.EndsAt.Sub(now)
+		}
+		c.Header("Retry-After", strconv.Itoa(max(1, int(retryAfter.Round(time.Second).Seconds()))))
+
+		message := window.Message
+		if message == "" {
+			message = defaultMaintenanceMessage
+		}
+		response.Result(c, http.StatusServiceUnavailable, serviceCode, response.CaseCodeMaintenance, window, message)
+		c.Abort()
+	}
+}
+
+// current returns the cached window, reading Redis at most once per refresh
+// interval. Redis is read without holding the lock, so a slow read never blocks
+// the requests served from the cache.
+func (s *maintenanceState) current(ctx context.Context, config MaintenanceConfig, now time.Time) *MaintenanceWindow {
+	s.mu.Lock()
+	fresh := !s.checkedAt.IsZero() && now.Sub(s.checkedAt) < config.RefreshInterval
+	if fresh || (s.refreshing && !s.checkedAt.IsZero()) {
+		window := s.window
+		s.mu.Unlock()
+		return window
+	}
+	s.refreshing = true
+	s.mu.Unlock()
+
+	window, err := ActiveMaintenance(config.ServiceCode, now)
+	if err != nil {
+		logger.WarnContext(ctx, "Failed to read maintenance flags, serving requests", logger.Fields{"error": err.Error()})
+		window = nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refreshing = false
+	if now.Before(s.checkedAt) {
+		// A later read has already been stored
+		return window
+	}
+	s.window = window
+	s.checkedAt = now
+	return window
+}
+
+// maintenanceBypassed reports whether a bypass rule lets the request through
+func maintenanceBypassed(c *gin.Context, config MaintenanceConfig, bypassIPs *ipwhitelist.Trie) bool {
+	if token := c.GetHeader(MaintenanceBypassHeader); token != "" {
+		for _, allowed := range config.BypassTokens {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(allowed)) == 1 {
+				return true
+			}
+		}
+	}
+	for _, role := range config.BypassRoles {
+		if HasRole(c, role) {
+			return true
+		}
+	}
+	addr, err := netip.ParseAddr(ClientIP(c))
+	return err == nil && bypassIPs.Contains(addr)
+}
+
+func maintenanceScope(serviceCode string) string {
+	if serviceCode == "" {
+		return "global"
+	}
+	return "service:" + serviceCode
+}
@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	response "github.com/writdev-alt/portal-api-shared/responses"
+)
+
+func TestMaintenance(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupRedisTest(t)
+
+	now := time.Now()
+	router := gin.New()
+	router.GET("/", Maintenance(MaintenanceConfig{
+		ServiceCode:     response.ServiceCodeWithdrawal,
+		BypassTokens:    []string{"ops-token"},
+		BypassIPs:       []string{"198.51.100.0/24"},
+		RefreshInterval: time.Nanosecond,
+		Clock:           func() time.Time { return now },
+	}), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	send := func(remoteAddr, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		if token != "" {
+			req.Header.Set(MaintenanceBypassHeader, token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := send("203.0.113.5:1234", ""); w.Code != http.StatusNoContent {
+		t.Fatalf("no maintenance: status = %d", w.Code)
+	}
+
+	// Another service's maintenance does not apply
+	if err := EnableMaintenance(response.ServiceCodeDeposit, ""); err != nil {
+		t.Fatalf("EnableMaintenance returned error: %v", err)
+	}
+	now = now.Add(time.Second)
+	if w := send("203.0.113.5:1234", ""); w.Code != http.StatusNoContent {
+		t.Errorf("other service in maintenance: status = %d", w.Code)
+	}
+
+	err := ScheduleMaintenance(MaintenanceWindow{
+		ServiceCode: response.ServiceCodeWithdrawal,
+		Message:     "Withdrawals are paused for a bank upgrade",
+		StartsAt:    now.Add(time.Minute),
+		EndsAt:      now.Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("ScheduleMaintenance returned error: %v", err)
+	}
+	now = now.Add(time.Second)
+	if w := send("203.0.113.5:1234", ""); w.Code != http.StatusNoContent {
+		t.Errorf("before the window: status = %d", w.Code)
+	}
+
+	now = now.Add(30*time.Minute - time.Second)
+	w := send("203.0.113.5:1234", "")
+	if w.Code != http.StatusServiceUnavailable || responseCaseCode(t, w) != response.CaseCodeMaintenance {
+		t.Errorf("during the window: status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Retry-After") != "1800" {
+		t.Errorf("Retry-After = %s", w.Header().Get("Retry-After"))
+	}
+
+	if w := send("203.0.113.5:1234", "ops-token"); w.Code != http.StatusNoContent {
+		t.Errorf("bypass token: status = %d", w.Code)
+	}
+	if w := send("203.0.113.5:1234", "wrong"); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("wrong bypass token: status = %d", w.Code)
+	}
+	if w := send("198.51.100.7:1234", ""); w.Code != http.StatusNoContent {
+		t.Errorf("bypass IP: status = %d", w.Code)
+	}
+
+	if err := DisableMaintenance(response.ServiceCodeWithdrawal); err != nil {
+		t.Fatalf("DisableMaintenance returned error: %v", err)
+	}
+	if err := EnableMaintenance("", ""); err != nil {
+		t.Fatalf("EnableMaintenance returned error: %v", err)
+	}
+	now = now.Add(time.Second)
+	w = send("203.0.113.5:1234", "")
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") != "300" {
+		t.Errorf("global maintenance: status = %d, Retry-After = %s", w.Code, w.Header().Get("Retry-After"))
+	}
+}
+
+func TestMaintenanceState_RefreshInFlight(t *testing.T) {
+	setupRedisTest(t)
+	if err := EnableMaintenance("", ""); err != nil {
+		t.Fatalf("EnableMaintenance returned error: %v", err)
+	}
+
+	config := MaintenanceConfig{RefreshInterval: time.Second}
+	now := time.Now()
+	state := &maintenanceState{checkedAt: now.Add(-time.Minute), refreshing: true}
+
+	// While another request reads Redis the stale window is served
+	if window := state.current(context.Background(), config, now); window != nil {
+		t.Errorf("window = %+v, expected the cached window during a refresh", window)
+	}
+
+	state.refreshing = false
+	if window := state.current(context.Background(), config, now); window == nil {
+		t.Error("expected the window read from Redis once no refresh is in flight")
+	}
+}
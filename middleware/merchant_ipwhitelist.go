@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/writdev-alt/portal-api-shared/ipwhitelist"
+	response "github.com/writdev-alt/portal-api-shared/responses"
+)
+
+// MerchantIPWhitelist restricts merchants, and API keys with their own list, to the
+// IPs they registered. It runs after APIKeyAuth or Auth, which set the merchant.
+func MerchantIPWhitelist(whitelist *ipwhitelist.Whitelist) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		owner := ipwhitelist.Owner{MerchantID: GetMerchantID(c)}
+		if owner.MerchantID == "" {
+			response.ForbiddenError(c, "Merchant required")
+			c.Abort()
+			return
+		}
+		if key, ok := CurrentAPIKey(c); ok {
+			owner.APIKeyID = key.UUID
+		}
+
+		realIP := ClientIP(c)
+		allowed, err := whitelist.Allowed(owner, realIP)
+		if err != nil {
+			response.Result(c, http.StatusServiceUnavailable, response.ServiceCodeIPWhitelist, response.CaseCodeServiceUnavailable, nil, "IP whitelist unavailable")
+			c.Abort()
+			return
+		}
+		if !allowed {
+			response.Result(c, http.StatusForbidden, response.ServiceCodeIPWhitelist, response.CaseCodePermissionDenied, nil, "IP address not whitelisted")
+			c.Abort()
+			return
+		}
+
+		c.Set("real_ip", realIP)
+		c.Next()
+	}
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/writdev-alt/portal-api-shared/ipwhitelist"
+	response "github.com/writdev-alt/portal-api-shared/responses"
+)
+
+// merchantLists is an ipwhitelist.Source for tests
+type merchantLists map[string][]string
+
+func (l merchantLists) Entries(owner ipwhitelist.Owner) ([]string, error) {
+	return l[owner.String()], nil
+}
+
+func TestMerchantIPWhitelist(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	whitelist := ipwhitelist.New(merchantLists{"merchant:m-1": {"198.51.100.0/24"}}, ipwhitelist.Options{})
+
+	router := gin.New()
+	router.GET("/", func(c *gin.Context) {
+		c.Set(ContextKeyMerchantID, c.Query("merchant"))
+	}, MerchantIPWhitelist(whitelist), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	send := func(merchant, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/?merchant="+merchant, nil)
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := send("m-1", "198.51.100.7:1234"); w.Code != http.StatusNoContent {
+		t.Errorf("whitelisted IP: status = %d", w.Code)
+	}
+	w := send("m-1", "203.0.113.7:1234")
+	if w.Code != http.StatusForbidden || responseCaseCode(t, w) != response.CaseCodePermissionDenied {
+		t.Errorf("other IP: status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if w := send("", "198.51.100.7:1234"); w.Code != http.StatusForbidden {
+		t.Errorf("no merchant: status = %d", w.Code)
+	}
+}
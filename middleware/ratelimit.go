@@ -0,0 +1,137 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/writdev-alt/portal-api-shared/logger"
+	"github.com/writdev-alt/portal-api-shared/redis"
+	response "github.com/writdev-alt/portal-api-shared/responses"
+)
+
+// RateLimitKeyFunc returns the bucket a request counts against. An empty key
+// skips rate limiting for the request.
+type RateLimitKeyFunc func(c *gin.Context) string
+
+// RateLimitPolicy limits requests per key. Apply one policy per route group,
+// e.g. a strict per-IP policy on login and a per-API-key policy on the merchant API.
+type RateLimitPolicy struct {
+	// Name separates the buckets of different policies, e.g. "login"
+	Name string
+	// Limit is the number of requests allowed per Period
+	Limit  int
+	Period time.Duration
+	// Burst is the number of requests allowed at once. Defaults to Limit.
+	Burst int
+	// Key selects the bucket. Defaults to RateLimitByIP.
+	Key RateLimitKeyFunc
+	// Clock overrides the Redis server clock the limiter uses by default. Only
+	// meant for tests; application clocks drift between instances.
+	Clock func() time.Time
+}
+
+// RateLimitByIP keys requests by client IP
+func RateLimitByIP(c *gin.Context) string {
+	return "ip:" + ClientIP(c)
+}
+
+// RateLimitByUser keys requests by authenticated user, falling back to the client IP
+func RateLimitByUser(c *gin.Context) string {
+	if userID := GetUserID(c); userID != "" {
+		return "user:" + userID
+	}
+	return RateLimitByIP(c)
+}
+
+// RateLimitByAPIKey keys requests by API key prefix, falling back to the client IP
+func RateLimitByAPIKey(c *gin.Context) string {
+	if key, ok := CurrentAPIKey(c); ok {
+		return "apikey:" + key.Prefix
+	}
+	return RateLimitByIP(c)
+}
+
+// RateLimitByRoute keys requests by route, shared by every client
+func RateLimitByRoute(c *gin.Context) string {
+	return "route:" + c.Request.Method + " " + c.FullPath()
+}
+
+// RateLimitKeys combines key functions, e.g. RateLimitKeys(RateLimitByRoute, RateLimitByUser)
+// for a per-user limit on each route
+func RateLimitKeys(fns ...RateLimitKeyFunc) RateLimitKeyFunc {
+	return func(c *gin.Context) string {
+		parts := make([]string, 0, len(fns))
+		for _, fn := range fns {
+			key := fn(c)
+			if key == "" {
+				return ""
+			}
+			parts = append(parts, key)
+		}
+		return strings.Join(parts, "|")
+	}
+}
+
+// RateLimit enforces a policy with GCRA in Redis and sets the RateLimit-Limit,
+// RateLimit-Remaining, RateLimit-Reset and RateLimit-Policy headers. Rejected
+// requests get a 429 with Retry-After. When Redis is unavailable requests are let
+// through, so an outage of the limiter does not take the API down with it.
+//
+// RateLimit panics if Limit or Period is not positive, so a misconfigured policy
+// fails at startup instead of on every request.
+func RateLimit(policy RateLimitPolicy) gin.HandlerFunc {
+	if policy.Limit <= 0 || policy.Period <= 0 {
+		panic(fmt.Sprintf("middleware: rate limit policy %q needs a positive Limit and Period", policy.Name))
+	}
+	if policy.Burst <= 0 {
+		policy.Burst = policy.Limit
+	}
+	if policy.Key == nil {
+		policy.Key = RateLimitByIP
+	}
+	header := fmt.Sprintf("%d;w=%d", policy.Limit, int(policy.Period.Seconds()))
+
+	return func(c *gin.Context) {
+		key := policy.Key(c)
+		if key == "" || !redis.IsEnabled() {
+			c.Next()
+			return
+		}
+
+		var now time.Time
+		if policy.Clock != nil {
+			now = policy.Clock()
+		}
+		result, err := redis.AllowRate(policy.Name+":"+key, policy.Limit, policy.Burst, policy.Period, now)
+		if err != nil {
+			logger.WarnContext(c.Request.Context(), "Rate limit check failed", logger.Fields{"policy": policy.Name, "error": err.Error()})
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Limit", strconv.Itoa(policy.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+		c.Header("RateLimit-Policy", header)
+
+		if !result.Allowed {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			response.Result(c, http.StatusTooManyRequests, response.ServiceCodeCommon, response.CaseCodeRateLimitExceeded, nil, "Too many requests")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// ceilSeconds rounds a duration up to whole seconds
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
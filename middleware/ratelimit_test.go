@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupRedisTest(t)
+
+	now := time.Now()
+	router := gin.New()
+	router.GET("/", RateLimit(RateLimitPolicy{
+		Name:   "test",
+		Limit:  2,
+		Period: time.Minute,
+		Clock:  func() time.Time { return now },
+	}), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	send := func(ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for i, remaining := range []string{"1", "0"} {
+		w := send("203.0.113.1")
+		if w.Code != http.StatusNoContent || w.Header().Get("RateLimit-Remaining") != remaining {
+			t.Errorf("request %d: status = %d, RateLimit-Remaining = %s", i, w.Code, w.Header().Get("RateLimit-Remaining"))
+		}
+	}
+
+	w := send("203.0.113.1")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "30" {
+		t.Errorf("status = %d, Retry-After = %s", w.Code, w.Header().Get("Retry-After"))
+	}
+	if w.Header().Get("RateLimit-Policy") != "2;w=60" {
+		t.Errorf("RateLimit-Policy = %s", w.Header().Get("RateLimit-Policy"))
+	}
+
+	// Other clients have their own bucket
+	if w := send("203.0.113.2"); w.Code != http.StatusNoContent {
+		t.Errorf("other client: status = %d", w.Code)
+	}
+
+	// One request is replenished every Period / Limit
+	now = now.Add(30 * time.Second)
+	if w := send("203.0.113.1"); w.Code != http.StatusNoContent {
+		t.Errorf("after replenish: status = %d", w.Code)
+	}
+}
+
+func TestRateLimit_InvalidPolicy(t *testing.T) {
+	tests := []RateLimitPolicy{
+		{Name: "zero limit", Period: time.Minute},
+		{Name: "zero period", Limit: 10},
+		{Name: "negative period", Limit: 10, Period: -time.Second},
+	}
+	for _, policy := range tests {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: RateLimit should panic", policy.Name)
+				}
+			}()
+			RateLimit(policy)
+		}()
+	}
+}
+
+func TestRateLimit_RedisClock(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mr := setupRedisTest(t)
+
+	now := time.Now()
+	mr.SetTime(now)
+	router := gin.New()
+	router.GET("/", RateLimit(RateLimitPolicy{Name: "test", Limit: 1, Period: time.Minute}), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	send := func() int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "203.0.113.1:1234"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := send(); code != http.StatusNoContent {
+		t.Errorf("first request: status = %d", code)
+	}
+	if code := send(); code != http.StatusTooManyRequests {
+		t.Errorf("second request: status = %d, expected %d", code, http.StatusTooManyRequests)
+	}
+
+	// Replenishment follows the Redis server clock, not the application one
+	mr.SetTime(now.Add(time.Minute))
+	if code := send(); code != http.StatusNoContent {
+		t.Errorf("after replenish: status = %d", code)
+	}
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/writdev-alt/portal-api-shared/rbac"
+	response "github.com/writdev-alt/portal-api-shared/responses"
+)
+
+// RequirePermission requires the authenticated user to hold every listed permission,
+// either directly in the token or through their roles as resolved by the package-level
+// authorizer (see rbac.SetDefault). Use it after AuthMiddleware.
+func RequirePermission(permissions ...string) gin.HandlerFunc {
+	return RequirePermissionWith(nil, permissions...)
+}
+
+// RequirePermissionWith is RequirePermission with a specific authorizer.
+// A nil authorizer uses the package-level one.
+func RequirePermissionWith(authz *rbac.Authorizer, permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		a := authz
+		if a == nil {
+			a = rbac.Default()
+		}
+
+		allowed, err := a.Allowed(GetRoles(c), GetPermissions(c), permissions...)
+		if err != nil {
+			response.Result(c, http.StatusInternalServerError, response.ServiceCodePermission, response.CaseCodeInternalError, nil, "Failed to resolve permissions")
+			c.Abort()
+			return
+		}
+		if !allowed {
+			response.ForbiddenError(c, "Permission denied")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireAnyRole requires the authenticated user to have at least one of the roles
+func RequireAnyRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !slices.ContainsFunc(roles, func(role string) bool { return HasRole(c, role) }) {
+			response.ForbiddenError(c, "Permission denied")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	sharedjwt "github.com/writdev-alt/portal-api-shared/jwt"
+	"github.com/writdev-alt/portal-api-shared/rbac"
+	response "github.com/writdev-alt/portal-api-shared/responses"
+)
+
+func TestRequirePermission(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := newTestManager(t, nil)
+	authz := rbac.NewAuthorizer(rbac.NewMemoryStore(map[string][]string{
+		"finance": {"withdrawal.*"},
+	}))
+
+	router := gin.New()
+	router.GET("/", Auth(m), RequirePermissionWith(authz, "withdrawal.approve"), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	finance, _ := m.IssueAccessToken(sharedjwt.Identity{UUID: uuid.New(), Roles: []string{"finance"}})
+	if w := performRequest(router, finance); w.Code != http.StatusNoContent {
+		t.Errorf("status = %d, expected %d", w.Code, http.StatusNoContent)
+	}
+
+	support, _ := m.IssueAccessToken(sharedjwt.Identity{UUID: uuid.New(), Roles: []string{"support"}})
+	w := performRequest(router, support)
+	if w.Code != http.StatusForbidden || responseCaseCode(t, w) != response.CaseCodePermissionDenied {
+		t.Errorf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRequireAnyRole(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := newTestManager(t, nil)
+
+	router := gin.New()
+	router.GET("/", Auth(m), RequireAnyRole("admin", "finance"), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	finance, _ := m.IssueAccessToken(sharedjwt.Identity{UUID: uuid.New(), Roles: []string{"finance"}})
+	if w := performRequest(router, finance); w.Code != http.StatusNoContent {
+		t.Errorf("status = %d, expected %d", w.Code, http.StatusNoContent)
+	}
+
+	support, _ := m.IssueAccessToken(sharedjwt.Identity{UUID: uuid.New(), Roles: []string{"support"}})
+	if w := performRequest(router, support); w.Code != http.StatusForbidden {
+		t.Errorf("status = %d, expected %d", w.Code, http.StatusForbidden)
+	}
+}
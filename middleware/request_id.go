@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/writdev-alt/portal-api-shared/logger"
+	response "github.com/writdev-alt/portal-api-shared/responses"
+)
+
+// RequestIDHeader carries the request ID in requests and responses
+const RequestIDHeader = "X-Request-ID"
+
+// ContextKeyRequestID is set by RequestID
+const ContextKeyRequestID = "request_id"
+
+const maxRequestIDLength = 128
+
+// RequestIDConfig configures RequestID
+type RequestIDConfig struct {
+	// IncludeInBody adds requestId to CommonResponse bodies
+	IncludeInBody bool
+	// Generate creates IDs for requests without a valid one. Defaults to UUIDs.
+	Generate func() string
+}
+
+// RequestID accepts the caller's X-Request-ID or generates one, echoes it in the
+// response header and stores it in the gin context and the request context. Log
+// entries written with c.Request.Context(), e.g. through logger.InfoContext,
+// carry it. Register it first so every other middleware sees it.
+func RequestID(config RequestIDConfig) gin.HandlerFunc {
+	if config.Generate == nil {
+		config.Generate = uuid.NewString
+	}
+
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = config.Generate()
+		}
+
+		c.Set(ContextKeyRequestID, requestID)
+		if config.IncludeInBody {
+			c.Set(response.ContextKeyRequestID, requestID)
+		}
+		c.Request = c.Request.WithContext(logger.WithRequestID(c.Request.Context(), requestID))
+		c.Header(RequestIDHeader, requestID)
+
+		c.Next()
+	}
+}
+
+// GetRequestID returns the ID of the current request
+func GetRequestID(c *gin.Context) string {
+	return c.GetString(ContextKeyRequestID)
+}
+
+// RequestIDTransport forwards the request ID of the outgoing request's context as
+// X-Request-ID, so calls to other services can be correlated:
+//
+//	client := &http.Client{Transport: &middleware.RequestIDTransport{}}
+//	req, _ := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, url, nil)
+type RequestIDTransport struct {
+	// Base performs the requests. Defaults to http.DefaultTransport.
+	Base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *RequestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	requestID := logger.RequestIDFromContext(req.Context())
+	if requestID == "" || req.Header.Get(RequestIDHeader) != "" {
+		return base.RoundTrip(req)
+	}
+	// RoundTrippers must not modify the caller's request
+	req = req.Clone(req.Context())
+	req.Header.Set(RequestIDHeader, requestID)
+	return base.RoundTrip(req)
+}
+
+// validRequestID accepts short IDs of safe characters, so caller supplied IDs
+// cannot inject content into logs or headers
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range requestID {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
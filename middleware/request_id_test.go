@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/writdev-alt/portal-api-shared/logger"
+	response "github.com/writdev-alt/portal-api-shared/responses"
+)
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var out bytes.Buffer
+	logger.SetOutput(&out)
+	t.Cleanup(func() { logger.SetOutput(os.Stderr) })
+
+	router := gin.New()
+	router.Use(RequestID(RequestIDConfig{IncludeInBody: true}), AccessLog(AccessLogConfig{}))
+	router.GET("/", func(c *gin.Context) {
+		logger.InfoContext(c.Request.Context(), "handling", nil)
+		response.OkWithData(c, GetRequestID(c))
+	})
+
+	send := func(requestID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if requestID != "" {
+			req.Header.Set(RequestIDHeader, requestID)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send("req-123")
+	var body response.CommonResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if w.Header().Get(RequestIDHeader) != "req-123" || body.RequestID != "req-123" || body.Data != "req-123" {
+		t.Errorf("header = %s, body = %s", w.Header().Get(RequestIDHeader), w.Body.String())
+	}
+
+	// Every entry of the request carries the ID
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected two entries, got %s", out.String())
+	}
+	for _, line := range lines {
+		var entry struct {
+			RequestID string `json:"requestId"`
+		}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil || entry.RequestID != "req-123" {
+			t.Errorf("entry without request ID: %s", line)
+		}
+	}
+
+	for _, requestID := range []string{"", "bad id\nwith newline", strings.Repeat("a", 200)} {
+		generated := send(requestID).Header().Get(RequestIDHeader)
+		if generated == "" || generated == requestID || len(generated) != 36 {
+			t.Errorf("request ID %q: generated %q", requestID, generated)
+		}
+	}
+}
+
+func TestRequestIDTransport(t *testing.T) {
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get(RequestIDHeader)
+	}))
+	defer server.Close()
+
+	req, err := http.NewRequestWithContext(logger.WithRequestID(t.Context(), "req-123"), http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: &RequestIDTransport{}}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if received != "req-123" {
+		t.Errorf("received %s = %q", RequestIDHeader, received)
+	}
+	if req.Header.Get(RequestIDHeader) != "" {
+		t.Error("expected the caller's request to be left untouched")
+	}
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+
+	sharedjwt "github.com/writdev-alt/portal-api-shared/jwt"
+	response "github.com/writdev-alt/portal-api-shared/responses"
+)
+
+// ServiceAuthMiddleware authenticates internal services by their client-credential
+// token and requires every listed scope. A nil manager uses the package-level one.
+func ServiceAuthMiddleware(m *sharedjwt.Manager, scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenString, ok := bearerToken(c)
+		if !ok {
+			response.UnauthorizedError(c, "Service token required")
+			c.Abort()
+			return
+		}
+
+		manager, ok := resolveManager(c, m)
+		if !ok {
+			return
+		}
+
+		claims, err := manager.ValidateServiceToken(tokenString)
+		if err != nil {
+			abortTokenError(c, err, "Invalid service token")
+			return
+		}
+
+		for _, scope := range scopes {
+			if !claims.Custom.HasScope(scope) {
+				response.ForbiddenError(c, "Missing scope: "+scope)
+				c.Abort()
+				return
+			}
+		}
+
+		c.Set(ContextKeyServiceID, claims.Custom.ClientID)
+		c.Set(ContextKeyServiceScopes, claims.Custom.Scopes)
+
+		c.Next()
+	}
+}
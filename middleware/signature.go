@@ -0,0 +1,152 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/writdev-alt/portal-api-shared/crypto"
+	"github.com/writdev-alt/portal-api-shared/redis"
+	response "github.com/writdev-alt/portal-api-shared/responses"
+)
+
+// Request signing headers
+const (
+	SignatureKeyIDHeader     = "X-Key-ID"
+	SignatureTimestampHeader = "X-Timestamp"
+	SignatureNonceHeader     = "X-Nonce"
+	SignatureHeader          = "X-Signature"
+)
+
+const (
+	defaultSignatureMaxSkew = 5 * time.Minute
+	defaultMaxSignedBody    = 10 << 20
+)
+
+// SigningKey is the shared secret a caller signs requests with
+type SigningKey struct {
+	Secret []byte
+	// MerchantID, when set, is stored in the context like APIKeyAuth does
+	MerchantID string
+}
+
+// SignatureConfig configures RequestSignature
+type SignatureConfig struct {
+	// LookupKey resolves the X-Key-ID header to a signing key, returning nil for unknown keys
+	LookupKey func(keyID string) (*SigningKey, error)
+	// MaxSkew is how far X-Timestamp may be from the server clock. Defaults to 5 minutes.
+	MaxSkew time.Duration
+	// MaxBodyBytes limits the signed body size. Defaults to 10 MiB.
+	MaxBodyBytes int64
+	// Clock returns the current time. Defaults to time.Now.
+	Clock func() time.Time
+}
+
+// RequestSignature verifies an HMAC-SHA256 signature (see crypto.SignRequest) over the
+// method, path, X-Timestamp, X-Nonce and body hash. Requests outside the clock-skew
+// window or reusing a nonce are rejected; nonces are remembered in Redis for twice
+// the skew window, which covers every timestamp that could still be accepted.
+func RequestSignature(config SignatureConfig) gin.HandlerFunc {
+	if config.LookupKey == nil {
+		panic("middleware: request signature needs a LookupKey")
+	}
+	if config.MaxSkew <= 0 {
+		config.MaxSkew = defaultSignatureMaxSkew
+	}
+	if config.MaxBodyBytes <= 0 {
+		config.MaxBodyBytes = defaultMaxSignedBody
+	}
+	if config.Clock == nil {
+		config.Clock = time.Now
+	}
+
+	return func(c *gin.Context) {
+		keyID := c.GetHeader(SignatureKeyIDHeader)
+		timestamp := c.GetHeader(SignatureTimestampHeader)
+		nonce := c.GetHeader(SignatureNonceHeader)
+		signature := c.GetHeader(SignatureHeader)
+		if keyID == "" || timestamp == "" || nonce == "" || signature == "" {
+			abortInvalidSignature(c, "Missing signature headers")
+			return
+		}
+
+		unix, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			abortInvalidSignature(c, "Invalid request timestamp")
+			return
+		}
+		if skew := config.Clock().Sub(time.Unix(unix, 0)); skew > config.MaxSkew || skew < -config.MaxSkew {
+			abortInvalidSignature(c, "Request timestamp outside the allowed window")
+			return
+		}
+
+		key, err := config.LookupKey(keyID)
+		if err != nil {
+			response.Result(c, http.StatusInternalServerError, response.ServiceCodeAuth, response.CaseCodeInternalError, nil, "Failed to resolve signing key")
+			c.Abort()
+			return
+		}
+		if key == nil {
+			abortInvalidSignature(c, "Invalid signature")
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, config.MaxBodyBytes))
+		if isBodyTooLarge(err) {
+			response.Result(c, http.StatusRequestEntityTooLarge, response.ServiceCodeCommon, response.CaseCodeInvalidValue, nil, "Request body too large")
+			c.Abort()
+			return
+		}
+		if err != nil {
+			response.Result(c, http.StatusBadRequest, response.ServiceCodeCommon, response.CaseCodeInvalidValue, nil, "Failed to read request body")
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		if !crypto.VerifyRequestSignature(key.Secret, signature, c.Request.Method, c.Request.URL.RequestURI(), timestamp, nonce, body) {
+			abortInvalidSignature(c, "Invalid signature")
+			return
+		}
+
+		// Only signed nonces are recorded, so forged requests cannot burn a caller's nonces
+		if !redis.IsEnabled() {
+			response.Result(c, http.StatusServiceUnavailable, response.ServiceCodeAuth, response.CaseCodeServiceUnavailable, nil, "Replay protection is unavailable")
+			c.Abort()
+			return
+		}
+		fresh, err := redis.ClaimNonce("signature:"+keyID, nonce, 2*config.MaxSkew)
+		if err != nil {
+			response.Result(c, http.StatusServiceUnavailable, response.ServiceCodeAuth, response.CaseCodeServiceUnavailable, nil, "Replay protection is unavailable")
+			c.Abort()
+			return
+		}
+		if !fresh {
+			abortInvalidSignature(c, "Nonce has already been used")
+			return
+		}
+
+		if key.MerchantID != "" {
+			c.Set(ContextKeyMerchantID, key.MerchantID)
+		}
+
+		c.Next()
+	}
+}
+
+// abortInvalidSignature rejects a request whose signature cannot be trusted
+func abortInvalidSignature(c *gin.Context, message string) {
+	response.Result(c, http.StatusUnauthorized, response.ServiceCodeAuth, response.CaseCodeInvalidToken, nil, message)
+	c.Abort()
+}
+
+// isBodyTooLarge reports whether a body read failed on the http.MaxBytesReader limit
+func isBodyTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	goredis "github.com/redis/go-redis/v9"
+
+	"github.com/writdev-alt/portal-api-shared/crypto"
+	"github.com/writdev-alt/portal-api-shared/redis"
+)
+
+func setupRedisTest(t *testing.T) *miniredis.Miniredis {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	redis.SetClient(goredis.NewClient(&goredis.Options{Addr: mr.Addr()}))
+	t.Cleanup(func() { redis.SetClient(nil) })
+	return mr
+}
+
+func TestRequestSignature(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupRedisTest(t)
+
+	secret := []byte("merchant-secret")
+	now := time.Now()
+	router := gin.New()
+	router.POST("/deposits", RequestSignature(SignatureConfig{
+		LookupKey: func(keyID string) (*SigningKey, error) {
+			if keyID != "merchant-1" {
+				return nil, nil
+			}
+			return &SigningKey{Secret: secret, MerchantID: "merchant-1"}, nil
+		},
+		Clock: func() time.Time { return now },
+	}), func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		if GetMerchantID(c) != "merchant-1" || string(body) != `{"amount":1000}` {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	send := func(keyID, nonce string, ts time.Time, body string, tamper bool) int {
+		timestamp := strconv.FormatInt(ts.Unix(), 10)
+		signature := crypto.SignRequest(secret, http.MethodPost, "/deposits?ref=1", timestamp, nonce, []byte(body))
+		if tamper {
+			body += " "
+		}
+		req := httptest.NewRequest(http.MethodPost, "/deposits?ref=1", strings.NewReader(body))
+		req.Header.Set(SignatureKeyIDHeader, keyID)
+		req.Header.Set(SignatureTimestampHeader, timestamp)
+		req.Header.Set(SignatureNonceHeader, nonce)
+		req.Header.Set(SignatureHeader, signature)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	body := `{"amount":1000}`
+	if code := send("merchant-1", "nonce-1", now, body, false); code != http.StatusNoContent {
+		t.Errorf("valid request: status = %d, expected %d", code, http.StatusNoContent)
+	}
+
+	tests := []struct {
+		name   string
+		keyID  string
+		nonce  string
+		ts     time.Time
+		tamper bool
+	}{
+		{"replayed nonce", "merchant-1", "nonce-1", now, false},
+		{"tampered body", "merchant-1", "nonce-2", now, true},
+		{"unknown key", "merchant-2", "nonce-3", now, false},
+		{"stale timestamp", "merchant-1", "nonce-4", now.Add(-10 * time.Minute), false},
+		{"future timestamp", "merchant-1", "nonce-5", now.Add(10 * time.Minute), false},
+	}
+	for _, tt := range tests {
+		if code := send(tt.keyID, tt.nonce, tt.ts, body, tt.tamper); code != http.StatusUnauthorized {
+			t.Errorf("%s: status = %d, expected %d", tt.name, code, http.StatusUnauthorized)
+		}
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("connection reset") }
+
+func TestRequestSignature_Body(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupRedisTest(t)
+
+	router := gin.New()
+	router.POST("/deposits", RequestSignature(SignatureConfig{
+		LookupKey:    func(string) (*SigningKey, error) { return &SigningKey{Secret: []byte("merchant-secret")}, nil },
+		MaxBodyBytes: 16,
+	}), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	send := func(body io.Reader) int {
+		req := httptest.NewRequest(http.MethodPost, "/deposits", body)
+		req.Header.Set(SignatureKeyIDHeader, "merchant-1")
+		req.Header.Set(SignatureTimestampHeader, strconv.FormatInt(time.Now().Unix(), 10))
+		req.Header.Set(SignatureNonceHeader, "nonce-1")
+		req.Header.Set(SignatureHeader, "signature")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := send(strings.NewReader(strings.Repeat("x", 17))); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("body too large: status = %d, expected %d", code, http.StatusRequestEntityTooLarge)
+	}
+	if code := send(failingReader{}); code != http.StatusBadRequest {
+		t.Errorf("read error: status = %d, expected %d", code, http.StatusBadRequest)
+	}
+}
+
+func TestRequestSignature_RequiresLookupKey(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("RequestSignature did not panic without a LookupKey")
+		}
+	}()
+	RequestSignature(SignatureConfig{})
+}
@@ -0,0 +1,285 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/writdev-alt/portal-api-shared/crypto"
+	sharedjwt "github.com/writdev-alt/portal-api-shared/jwt"
+	"github.com/writdev-alt/portal-api-shared/redis"
+	response "github.com/writdev-alt/portal-api-shared/responses"
+)
+
+// SNAP BI headers
+const (
+	SnapTimestampHeader  = "X-TIMESTAMP"
+	SnapSignatureHeader  = "X-SIGNATURE"
+	SnapClientKeyHeader  = "X-CLIENT-KEY"
+	SnapPartnerIDHeader  = "X-PARTNER-ID"
+	SnapExternalIDHeader = "X-EXTERNAL-ID"
+	SnapChannelIDHeader  = "CHANNEL-ID"
+)
+
+// Context keys set by SnapTransaction, which also sets ContextKeyServiceID and ContextKeyMerchantID
+const (
+	ContextKeySnapExternalID = "snap_external_id"
+	ContextKeySnapChannelID  = "snap_channel_id"
+)
+
+const defaultSnapMaxSkew = 5 * time.Minute
+
+// snapLocation is the day boundary for X-EXTERNAL-ID uniqueness (WIB, UTC+7)
+var snapLocation = time.FixedZone("WIB", 7*60*60)
+
+// SnapPartner is a SNAP BI partner registered with us
+type SnapPartner struct {
+	// ClientKey is sent as X-CLIENT-KEY when requesting tokens and as X-PARTNER-ID afterwards
+	ClientKey string
+	// PublicKey verifies the SHA256withRSA signature of access token requests
+	PublicKey *rsa.PublicKey
+	// ClientSecret verifies the HMAC-SHA512 signature of transactional requests
+	ClientSecret []byte
+	// MerchantID, when set, is stored in the context
+	MerchantID string
+	// Scopes are granted to the partner's access tokens
+	Scopes []string
+}
+
+// SnapConfig configures the SNAP BI handlers
+type SnapConfig struct {
+	// LookupPartner resolves a client key to a partner, returning nil for unknown partners
+	LookupPartner func(clientKey string) (*SnapPartner, error)
+	// Manager issues and validates access tokens. A nil manager uses the package-level one.
+	Manager *sharedjwt.Manager
+	// ServiceCode is the SNAP service code of the API, e.g. response.SnapServiceCodeTransferIntrabank
+	ServiceCode string
+	// MaxSkew is how far X-TIMESTAMP may be from the server clock. Defaults to 5 minutes.
+	MaxSkew time.Duration
+	// MaxBodyBytes limits the signed body size of transactional requests. Defaults to 10 MiB.
+	MaxBodyBytes int64
+	// Clock returns the current time. Defaults to time.Now.
+	Clock func() time.Time
+}
+
+// snapAccessTokenRequest is the body of the B2B access token request
+type snapAccessTokenRequest struct {
+	GrantType string `json:"grantType"`
+}
+
+// snapAccessTokenResponse is the SNAP B2B access token response
+type snapAccessTokenResponse struct {
+	AccessToken string `json:"accessToken"`
+	TokenType   string `json:"tokenType"`
+	ExpiresIn   string `json:"expiresIn"`
+}
+
+// withDefaults fills in the optional settings
+func (config SnapConfig) withDefaults() SnapConfig {
+	if config.MaxSkew <= 0 {
+		config.MaxSkew = defaultSnapMaxSkew
+	}
+	if config.MaxBodyBytes <= 0 {
+		config.MaxBodyBytes = defaultMaxSignedBody
+	}
+	if config.Clock == nil {
+		config.Clock = time.Now
+	}
+	return config
+}
+
+// SnapAccessTokenB2B serves POST /v1.0/access-token/b2b. Partners authenticate with
+// X-CLIENT-KEY, X-TIMESTAMP and a SHA256withRSA X-SIGNATURE over "clientKey|timestamp"
+// and receive a SNAP partner token (see jwt.IssueSnapToken), which ServiceAuthMiddleware
+// never accepts.
+func SnapAccessTokenB2B(config SnapConfig) gin.HandlerFunc {
+	config = config.withDefaults()
+	serviceCode := response.SnapServiceCodeAccessTokenB2B
+
+	return func(c *gin.Context) {
+		clientKey, timestamp, ok := snapRequiredHeaders(c, serviceCode, SnapClientKeyHeader, SnapTimestampHeader, SnapSignatureHeader)
+		if !ok {
+			return
+		}
+		if !snapCheckTimestamp(c, config, serviceCode, timestamp) {
+			return
+		}
+
+		var req snapAccessTokenRequest
+		if err := c.ShouldBindJSON(&req); err != nil || req.GrantType != "client_credentials" {
+			snapAbort(c, http.StatusBadRequest, serviceCode, response.CaseCodeInvalidFormat, "Invalid Field Format [grantType]")
+			return
+		}
+
+		partner, ok := snapLookupPartner(c, config, serviceCode, clientKey)
+		if !ok {
+			return
+		}
+		if partner.PublicKey == nil || !crypto.SnapAsymmetricVerify(partner.PublicKey, c.GetHeader(SnapSignatureHeader), clientKey, timestamp) {
+			snapAbort(c, http.StatusUnauthorized, serviceCode, response.CaseCodeUnauthorized, "Unauthorized. [Signature]")
+			return
+		}
+
+		manager, ok := snapManager(c, config, serviceCode)
+		if !ok {
+			return
+		}
+		token, err := manager.IssueSnapToken(partner.ClientKey, partner.Scopes)
+		if err != nil {
+			snapAbort(c, http.StatusInternalServerError, serviceCode, response.CaseCodeInternalError, "")
+			return
+		}
+
+		response.SnapResult(c, http.StatusOK, serviceCode, response.CaseCodeSuccess, snapAccessTokenResponse{
+			AccessToken: token.AccessToken,
+			TokenType:   token.TokenType,
+			ExpiresIn:   strconv.FormatInt(token.ExpiresIn, 10),
+		}, "")
+	}
+}
+
+// SnapTransaction authenticates SNAP BI transactional requests: it validates the
+// B2B access token, the HMAC-SHA512 X-SIGNATURE and X-TIMESTAMP, and rejects an
+// X-EXTERNAL-ID already used by the partner on the same day (WIB).
+func SnapTransaction(config SnapConfig) gin.HandlerFunc {
+	config = config.withDefaults()
+	serviceCode := config.ServiceCode
+
+	return func(c *gin.Context) {
+		partnerID, timestamp, ok := snapRequiredHeaders(c, serviceCode, SnapPartnerIDHeader, SnapTimestampHeader, SnapSignatureHeader, SnapExternalIDHeader)
+		if !ok {
+			return
+		}
+		if !snapCheckTimestamp(c, config, serviceCode, timestamp) {
+			return
+		}
+
+		accessToken, ok := bearerToken(c)
+		if !ok {
+			snapAbort(c, http.StatusUnauthorized, serviceCode, response.CaseCodeInvalidToken, "")
+			return
+		}
+		manager, ok := snapManager(c, config, serviceCode)
+		if !ok {
+			return
+		}
+		claims, err := manager.ValidateSnapToken(accessToken)
+		if sharedjwt.CaseCode(err) == response.CaseCodeServiceUnavailable {
+			snapAbort(c, http.StatusServiceUnavailable, serviceCode, response.CaseCodeServiceUnavailable, "")
+			return
+		}
+		if err != nil || claims.Custom.ClientID != partnerID {
+			snapAbort(c, http.StatusUnauthorized, serviceCode, response.CaseCodeInvalidToken, "")
+			return
+		}
+
+		partner, ok := snapLookupPartner(c, config, serviceCode, partnerID)
+		if !ok {
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, config.MaxBodyBytes))
+		if err != nil {
+			snapAbort(c, http.StatusRequestEntityTooLarge, serviceCode, response.CaseCodeInvalidValue, "Request body too large")
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		signature := c.GetHeader(SnapSignatureHeader)
+		if !crypto.SnapSymmetricVerify(partner.ClientSecret, signature, c.Request.Method, c.Request.URL.RequestURI(), accessToken, body, timestamp) {
+			snapAbort(c, http.StatusUnauthorized, serviceCode, response.CaseCodeUnauthorized, "Unauthorized. [Signature]")
+			return
+		}
+
+		// Only signed requests claim an external ID, so forged requests cannot burn them
+		externalID := c.GetHeader(SnapExternalIDHeader)
+		if !redis.IsEnabled() {
+			snapAbort(c, http.StatusServiceUnavailable, serviceCode, response.CaseCodeServiceUnavailable, "")
+			return
+		}
+		now := config.Clock().In(snapLocation)
+		endOfDay := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, snapLocation)
+		fresh, err := redis.ClaimNonce("snap_external_id:"+partnerID+":"+now.Format("20060102"), externalID, endOfDay.Sub(now)+config.MaxSkew)
+		if err != nil {
+			snapAbort(c, http.StatusServiceUnavailable, serviceCode, response.CaseCodeServiceUnavailable, "")
+			return
+		}
+		if !fresh {
+			snapAbort(c, http.StatusConflict, serviceCode, response.CaseCodeConflict, "")
+			return
+		}
+
+		c.Set(ContextKeyServiceID, partner.ClientKey)
+		c.Set(ContextKeyServiceScopes, claims.Custom.Scopes)
+		c.Set(ContextKeySnapExternalID, externalID)
+		c.Set(ContextKeySnapChannelID, c.GetHeader(SnapChannelIDHeader))
+		if partner.MerchantID != "" {
+			c.Set(ContextKeyMerchantID, partner.MerchantID)
+		}
+
+		c.Next()
+	}
+}
+
+// snapRequiredHeaders checks that every header is present and returns the first two
+func snapRequiredHeaders(c *gin.Context, serviceCode string, headers ...string) (string, string, bool) {
+	for _, header := range headers {
+		if c.GetHeader(header) == "" {
+			snapAbort(c, http.StatusBadRequest, serviceCode, response.CaseCodeRequiredField, "Invalid Mandatory Field ["+header+"]")
+			return "", "", false
+		}
+	}
+	return c.GetHeader(headers[0]), c.GetHeader(headers[1]), true
+}
+
+// snapCheckTimestamp rejects a malformed X-TIMESTAMP or one outside the skew window
+func snapCheckTimestamp(c *gin.Context, config SnapConfig, serviceCode, timestamp string) bool {
+	t, err := crypto.ParseSnapTimestamp(timestamp)
+	if err != nil {
+		snapAbort(c, http.StatusBadRequest, serviceCode, response.CaseCodeInvalidFormat, "Invalid Field Format ["+SnapTimestampHeader+"]")
+		return false
+	}
+	if skew := config.Clock().Sub(t); skew > config.MaxSkew || skew < -config.MaxSkew {
+		snapAbort(c, http.StatusUnauthorized, serviceCode, response.CaseCodeUnauthorized, "Unauthorized. ["+SnapTimestampHeader+"]")
+		return false
+	}
+	return true
+}
+
+// snapLookupPartner resolves a partner, rejecting unknown ones as unauthorized
+func snapLookupPartner(c *gin.Context, config SnapConfig, serviceCode, clientKey string) (*SnapPartner, bool) {
+	partner, err := config.LookupPartner(clientKey)
+	if err != nil {
+		snapAbort(c, http.StatusInternalServerError, serviceCode, response.CaseCodeInternalError, "")
+		return nil, false
+	}
+	if partner == nil {
+		snapAbort(c, http.StatusUnauthorized, serviceCode, response.CaseCodeUnauthorized, "Unauthorized. [Unknown client]")
+		return nil, false
+	}
+	return partner, true
+}
+
+// snapManager returns the configured manager or the package-level one
+func snapManager(c *gin.Context, config SnapConfig, serviceCode string) (*sharedjwt.Manager, bool) {
+	if config.Manager != nil {
+		return config.Manager, true
+	}
+	m, err := sharedjwt.Default()
+	if err != nil {
+		snapAbort(c, http.StatusInternalServerError, serviceCode, response.CaseCodeConfigurationError, "")
+		return nil, false
+	}
+	return m, true
+}
+
+// snapAbort answers with a SNAP BI error response
+func snapAbort(c *gin.Context, httpStatus int, serviceCode, caseCode, message string) {
+	response.SnapResult(c, httpStatus, serviceCode, caseCode, nil, message)
+	c.Abort()
+}
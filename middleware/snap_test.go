@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/writdev-alt/portal-api-shared/crypto"
+	response "github.com/writdev-alt/portal-api-shared/responses"
+)
+
+func TestSnap(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mr := setupRedisTest(t)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	partner := &SnapPartner{
+		ClientKey:    "partner-1",
+		PublicKey:    &key.PublicKey,
+		ClientSecret: []byte("partner-secret"),
+		MerchantID:   "merchant-1",
+	}
+	config := SnapConfig{
+		LookupPartner: func(clientKey string) (*SnapPartner, error) {
+			if clientKey != partner.ClientKey {
+				return nil, nil
+			}
+			return partner, nil
+		},
+		Manager:      newTestManager(t, nil),
+		ServiceCode:  response.SnapServiceCodeTransferIntrabank,
+		MaxBodyBytes: 1 << 10,
+	}
+
+	router := gin.New()
+	router.POST("/v1.0/access-token/b2b", SnapAccessTokenB2B(config))
+	router.POST("/v1.0/transfer-intrabank", SnapTransaction(config), func(c *gin.Context) {
+		response.SnapResult(c, http.StatusOK, config.ServiceCode, response.CaseCodeSuccess, gin.H{"merchantId": GetMerchantID(c)}, "")
+	})
+	router.GET("/", ServiceAuthMiddleware(config.Manager), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	// Request an access token
+	timestamp := crypto.FormatSnapTimestamp(time.Now())
+	signature, _ := crypto.SnapAsymmetricSign(key, partner.ClientKey, timestamp)
+	req := httptest.NewRequest(http.MethodPost, "/v1.0/access-token/b2b", strings.NewReader(`{"grantType":"client_credentials"}`))
+	req.Header.Set(SnapClientKeyHeader, partner.ClientKey)
+	req.Header.Set(SnapTimestampHeader, timestamp)
+	req.Header.Set(SnapSignatureHeader, signature)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var tokenResponse struct {
+		ResponseCode string `json:"responseCode"`
+		AccessToken  string `json:"accessToken"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &tokenResponse); err != nil || tokenResponse.ResponseCode != "2007300" {
+		t.Fatalf("access token: status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	// Partner tokens are not service tokens, so partners cannot call internal services
+	if w := performRequest(router, tokenResponse.AccessToken); w.Code != http.StatusUnauthorized {
+		t.Errorf("service auth with a SNAP token: status = %d, expected %d", w.Code, http.StatusUnauthorized)
+	}
+
+	transfer := func(externalID, body string, headers map[string]string) (int, string) {
+		if body == "" {
+			body = `{"partnerReferenceNo":"ref-1"}`
+		}
+		timestamp := crypto.FormatSnapTimestamp(time.Now())
+		signature, _ := crypto.SnapSymmetricSign(partner.ClientSecret, http.MethodPost, "/v1.0/transfer-intrabank", tokenResponse.AccessToken, []byte(body), timestamp)
+		req := httptest.NewRequest(http.MethodPost, "/v1.0/transfer-intrabank", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+tokenResponse.AccessToken)
+		req.Header.Set(SnapPartnerIDHeader, partner.ClientKey)
+		req.Header.Set(SnapTimestampHeader, timestamp)
+		req.Header.Set(SnapSignatureHeader, signature)
+		req.Header.Set(SnapExternalIDHeader, externalID)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var snap response.SnapResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &snap)
+		return w.Code, snap.ResponseCode
+	}
+
+	tests := []struct {
+		name       string
+		externalID string
+		body       string
+		headers    map[string]string
+		status     int
+		code       string
+	}{
+		{"valid", "ext-1", "", nil, http.StatusOK, "2001700"},
+		{"duplicate external ID", "ext-1", "", nil, http.StatusConflict, "4091700"},
+		{"missing partner ID", "ext-2", "", map[string]string{SnapPartnerIDHeader: ""}, http.StatusBadRequest, "4001702"},
+		{"invalid signature", "ext-3", "", map[string]string{SnapSignatureHeader: "invalid"}, http.StatusUnauthorized, "4011700"},
+		{"invalid token", "ext-4", "", map[string]string{"Authorization": "Bearer invalid"}, http.StatusUnauthorized, "4011701"},
+		{"body too large", "ext-5", `{"partnerReferenceNo":"` + strings.Repeat("x", 2<<10) + `"}`, nil, http.StatusRequestEntityTooLarge, "4131700"},
+	}
+	for _, tt := range tests {
+		status, code := transfer(tt.externalID, tt.body, tt.headers)
+		if status != tt.status || code != tt.code {
+			t.Errorf("%s: status = %d, responseCode = %s, expected %d %s", tt.name, status, code, tt.status, tt.code)
+		}
+	}
+
+	mr.Close()
+	if status, code := transfer("ext-6", "", nil); status != http.StatusServiceUnavailable || code != "5031700" {
+		t.Errorf("redis unavailable: status = %d, responseCode = %s, expected %d 5031700", status, code, http.StatusServiceUnavailable)
+	}
+}
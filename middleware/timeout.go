@@ -0,0 +1,230 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	response "github.com/writdev-alt/portal-api-shared/responses"
+)
+
+const defaultTimeout = 30 * time.Second
+
+var errHijackUnderTimeout = errors.New("hijacking is not supported under the timeout middleware")
+
+// TimeoutConfig configures Timeout
+type TimeoutConfig struct {
+	// Timeout is the deadline of the request context. Defaults to 30 seconds.
+	Timeout time.Duration
+	// ServiceCode is the service code of the timeout response. Defaults to ServiceCodeCommon.
+	ServiceCode string
+	// Message is the message of the timeout response
+	Message string
+}
+
+// timeoutWriter buffers the handler's response, so it can be replaced by the
+// timeout response without both reaching the client. The handler runs on its own
+// goroutine, so everything but the header map is guarded by mu.
+type timeoutWriter struct {
+	gin.ResponseWriter
+	header http.Header
+
+	mu       sync.Mutex
+	body     bytes.Buffer
+	status   int
+	timedOut bool
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.body.Len() == 0 {
+		w.status = code
+	}
+}
+
+func (w *timeoutWriter) WriteHeaderNow() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writeHeaderNow()
+}
+
+func (w *timeoutWriter) writeHeaderNow() {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+}
+
+// Write keeps buffering after a timeout, so middleware between Timeout and the
+// handler, such as Idempotency, still sees the completed response
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writeHeaderNow()
+	return w.body.Write(data)
+}
+
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writeHeaderNow()
+	return w.body.WriteString(s)
+}
+
+func (w *timeoutWriter) Status() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+func (w *timeoutWriter) Size() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.status == 0 {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *timeoutWriter) Written() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.status != 0
+}
+
+// Flush is a no-op, the response is sent once the handler returns
+func (w *timeoutWriter) Flush() {}
+
+func (w *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, errHijackUnderTimeout
+}
+
+// timeout marks the response as timed out unless the handler has already started
+// writing one, in which case that response is kept
+func (w *timeoutWriter) timeout() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.status != 0 {
+		return false
+	}
+	w.timedOut = true
+	return true
+}
+
+// Timeout gives the request context a deadline. Handlers must pass
+// c.Request.Context() to database queries and outbound calls so they stop when it
+// expires.
+//
+// The handler runs on its own goroutine with its response buffered. If it has not
+// started writing a response by the deadline, a 504 with CaseCodeTimeout is sent
+// right away (with "Connection: close") and whatever the handler writes afterwards
+// is dropped. A response the handler has started writing is always sent complete,
+// never replaced. Timeout still returns only after the handler does, because the
+// gin context is reused once the middleware returns.
+//
+// Register Timeout before Idempotency, i.e. further out: Idempotency then stores
+// the handler's real result even when the client got the 504, and a retry replays
+// it instead of running the mutation again. Registered the other way round,
+// Idempotency only sees the 504 and does not store it.
+//
+// Nested timeouts keep the earliest deadline; do not use it on streaming routes.
+func Timeout(config TimeoutConfig) gin.HandlerFunc {
+	if config.Timeout <= 0 {
+		config.Timeout = defaultTimeout
+	}
+	if config.ServiceCode == "" {
+		config.ServiceCode = response.ServiceCodeCommon
+	}
+	if config.Message == "" {
+		config.Message = "Request timed out"
+	}
+	responseCode := response.BuildResponseCode(http.StatusGatewayTimeout, config.ServiceCode, response.CaseCodeTimeout)
+
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), config.Timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		original := c.Writer
+		writer := &timeoutWriter{ResponseWriter: original, header: original.Header().Clone()}
+		c.Writer = writer
+		// Read before the handler starts, the context is not touched concurrently
+		requestID := c.GetString(response.ContextKeyRequestID)
+
+		done := make(chan struct{})
+		var panicked any
+		go func() {
+			defer close(done)
+			// Re-raised on the request goroutine, where recovery can handle it
+			defer func() { panicked = recover() }()
+			c.Next()
+		}()
+
+		timedOut := false
+		select {
+		case <-done:
+		case <-ctx.Done():
+			if writer.timeout() {
+				timedOut = true
+				writeTimeoutResponse(original, responseCode, config.Message, requestID)
+			}
+			<-done
+		}
+
+		c.Writer = original
+		if panicked != nil {
+			panic(panicked)
+		}
+		if timedOut {
+			c.Set(response.ContextKeyResponseCode, responseCode)
+			c.Abort()
+			return
+		}
+
+		header := original.Header()
+		for key := range header {
+			if _, ok := writer.header[key]; !ok {
+				header.Del(key)
+			}
+		}
+		for key, values := range writer.header {
+			header[key] = values
+		}
+		if writer.status != 0 {
+			original.WriteHeader(writer.status)
+		}
+		if writer.body.Len() > 0 {
+			_, _ = original.Write(writer.body.Bytes())
+		}
+	}
+}
+
+// writeTimeoutResponse sends the 504 straight to the client while the handler is
+// still running. It cannot go through the gin context, which the handler owns.
+func writeTimeoutResponse(w gin.ResponseWriter, responseCode int, message, requestID string) {
+	body, _ := json.Marshal(response.CommonResponse{
+		Code:      responseCode,
+		Message:   message,
+		RequestID: requestID,
+	})
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Connection", "close")
+	w.WriteHeader(http.StatusGatewayTimeout)
+	_, _ = w.Write(body)
+	w.Flush()
+}
@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	response "github.com/writdev-alt/portal-api-shared/responses"
+)
+
+func TestTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	api := router.Group("/", Timeout(TimeoutConfig{Timeout: time.Second}))
+	api.GET("/fast", func(c *gin.Context) {
+		c.Header("X-Handler", "fast")
+		c.JSON(http.StatusCreated, gin.H{"ok": true})
+	})
+	// A tighter deadline for one group
+	slow := api.Group("/reports", Timeout(TimeoutConfig{Timeout: 20 * time.Millisecond, ServiceCode: response.ServiceCodeTransaction}))
+	slow.GET("/export", func(c *gin.Context) {
+		c.Header("X-Handler", "slow")
+		select {
+		case <-c.Request.Context().Done():
+			// Gives up without writing a response
+		case <-time.After(time.Second):
+			t.Error("expected the request context to have the group deadline")
+			c.JSON(http.StatusOK, gin.H{"partial": true})
+		}
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fast", nil))
+	if w.Code != http.StatusCreated || w.Header().Get("X-Handler") != "fast" || w.Body.String() != `{"ok":true}` {
+		t.Errorf("fast handler: status = %d, headers = %v, body = %s", w.Code, w.Header(), w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/reports/export", nil))
+	if w.Code != http.StatusGatewayTimeout || responseCaseCode(t, w) != response.CaseCodeTimeout {
+		t.Errorf("slow handler: status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("X-Handler") != "" {
+		t.Error("expected the handler's headers to be discarded")
+	}
+}
+
+func TestTimeout_HandlerFinishesAfterDeadline(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupRedisTest(t)
+
+	calls := 0
+	router := gin.New()
+	// Timeout outside Idempotency, so the completed mutation is stored for retries
+	router.POST("/withdrawals",
+		Timeout(TimeoutConfig{Timeout: 20 * time.Millisecond}),
+		Idempotency(IdempotencyConfig{}),
+		func(c *gin.Context) {
+			calls++
+			// Ignores the request context and completes after the deadline
+			time.Sleep(100 * time.Millisecond)
+			response.Created(c, response.ServiceCodeWithdrawal, gin.H{"call": calls}, "")
+		},
+	)
+
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/withdrawals", strings.NewReader(`{"amount":1000}`))
+		req.Header.Set(IdempotencyKeyHeader, "key-1")
+		req.RemoteAddr = "203.0.113.1:1234"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send()
+	if w.Code != http.StatusGatewayTimeout || responseCaseCode(t, w) != response.CaseCodeTimeout {
+		t.Fatalf("first request: status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	w = send()
+	if w.Code != http.StatusCreated || w.Header().Get(IdempotentReplayedHeader) != "true" || calls != 1 {
+		t.Errorf("retry: status = %d, calls = %d, body = %s", w.Code, calls, w.Body.String())
+	}
+}
+
+func TestTimeout_KeepsStartedResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/", Timeout(TimeoutConfig{Timeout: 20 * time.Millisecond}), func(c *gin.Context) {
+		c.Status(http.StatusAccepted)
+		time.Sleep(50 * time.Millisecond)
+		_, _ = c.Writer.WriteString("done")
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusAccepted || w.Body.String() != "done" {
+		t.Errorf("status = %d, body = %s, expected the started response", w.Code, w.Body.String())
+	}
+}
+
+// flushRecorder signals the first flush of the response
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	flushed chan struct{}
+}
+
+func (r *flushRecorder) Flush() {
+	r.ResponseRecorder.Flush()
+	select {
+	case <-r.flushed:
+	default:
+		close(r.flushed)
+	}
+}
+
+func TestTimeout_AnswersAtDeadline(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	release := make(chan struct{})
+	router := gin.New()
+	router.GET("/", Timeout(TimeoutConfig{Timeout: 20 * time.Millisecond}), func(c *gin.Context) {
+		<-release
+		c.Status(http.StatusOK)
+	})
+
+	w := &flushRecorder{ResponseRecorder: httptest.NewRecorder(), flushed: make(chan struct{})}
+	served := make(chan struct{})
+	go func() {
+		defer close(served)
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	}()
+
+	// The 504 is flushed while the stuck handler is still running
+	select {
+	case <-w.flushed:
+		if w.Code != http.StatusGatewayTimeout || w.Header().Get("Connection") != "close" {
+			t.Errorf("status = %d, headers = %v", w.Code, w.Header())
+		}
+	case <-time.After(time.Second):
+		t.Error("expected the timeout response before the handler returned")
+	}
+	close(release)
+	<-served
+}
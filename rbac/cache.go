@@ -0,0 +1,68 @@
+package rbac
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/writdev-alt/portal-api-shared/redis"
+)
+
+const (
+	cacheKeyPrefix  = "rbac:role_permissions:"
+	defaultCacheTTL = 5 * time.Minute
+)
+
+// CachedStore caches the role permissions of another store in Redis. It reads
+// through to the store when Redis is not configured or unavailable.
+type CachedStore struct {
+	store Store
+	ttl   time.Duration
+}
+
+// NewCachedStore wraps a store with a Redis cache. A ttl of zero defaults to 5 minutes.
+func NewCachedStore(store Store, ttl time.Duration) *CachedStore {
+	if ttl <= 0 {
+		ttl = defaultCacheTTL
+	}
+	return &CachedStore{store: store, ttl: ttl}
+}
+
+// RolePermissions implements Store
+func (s *CachedStore) RolePermissions(role string) ([]string, error) {
+	if !redis.IsEnabled() {
+		return s.store.RolePermissions(role)
+	}
+
+	if cached, err := redis.Get(cacheKeyPrefix + role); err == nil && cached != "" {
+		var permissions []string
+		if err := json.Unmarshal([]byte(cached), &permissions); err == nil {
+			return permissions, nil
+		}
+	}
+
+	permissions, err := s.store.RolePermissions(role)
+	if err != nil {
+		return nil, err
+	}
+	if permissions == nil {
+		// Cache roles without permissions too, so unknown roles do not hit the store every time
+		permissions = []string{}
+	}
+	if data, err := json.Marshal(permissions); err == nil {
+		_ = redis.Set(cacheKeyPrefix+role, data, s.ttl)
+	}
+	return permissions, nil
+}
+
+// Invalidate drops the cached permissions of roles, e.g. after editing them
+func (s *CachedStore) Invalidate(roles ...string) error {
+	if !redis.IsEnabled() {
+		return nil
+	}
+	for _, role := range roles {
+		if err := redis.Delete(cacheKeyPrefix + role); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package rbac
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// GormTables names the tables read by GormStore
+type GormTables struct {
+	Roles           string // id, name
+	Permissions     string // id, name
+	RolePermissions string // role_id, permission_id
+}
+
+// DefaultGormTables matches the roles, permissions and role_has_permissions
+// tables shared with the admin portal
+var DefaultGormTables = GormTables{
+	Roles:           "roles",
+	Permissions:     "permissions",
+	RolePermissions: "role_has_permissions",
+}
+
+// GormStore is a Store reading role permissions from the database
+type GormStore struct {
+	db     *gorm.DB
+	tables GormTables
+}
+
+// NewGormStore creates a store reading from the default tables
+func NewGormStore(db *gorm.DB) *GormStore {
+	return NewGormStoreWithTables(db, DefaultGormTables)
+}
+
+// NewGormStoreWithTables creates a store reading from custom tables
+func NewGormStoreWithTables(db *gorm.DB, tables GormTables) *GormStore {
+	return &GormStore{db: db, tables: tables}
+}
+
+// RolePermissions implements Store
+func (s *GormStore) RolePermissions(role string) ([]string, error) {
+	var permissions []string
+	err := s.db.Table(s.tables.Permissions+" AS p").
+		Select("p.name").
+		Joins(fmt.Sprintf("JOIN %s AS rp ON rp.permission_id = p.id", s.tables.RolePermissions)).
+		Joins(fmt.Sprintf("JOIN %s AS r ON r.id = rp.role_id", s.tables.Roles)).
+		Where("r.name = ?", role).
+		Pluck("p.name", &permissions).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load role permissions: %w", err)
+	}
+	return permissions, nil
+}
@@ -0,0 +1,33 @@
+package rbac
+
+import "sync"
+
+// MemoryStore is a Store backed by a role to permissions map, for tests and
+// services with a fixed set of roles
+type MemoryStore struct {
+	mu    sync.RWMutex
+	roles map[string][]string
+}
+
+// NewMemoryStore creates a memory store from a role to permissions map
+func NewMemoryStore(roles map[string][]string) *MemoryStore {
+	s := &MemoryStore{roles: make(map[string][]string, len(roles))}
+	for role, permissions := range roles {
+		s.roles[role] = append([]string(nil), permissions...)
+	}
+	return s
+}
+
+// RolePermissions implements Store
+func (s *MemoryStore) RolePermissions(role string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]string(nil), s.roles[role]...), nil
+}
+
+// SetRolePermissions replaces the permissions of a role
+func (s *MemoryStore) SetRolePermissions(role string, permissions ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.roles[role] = append([]string(nil), permissions...)
+}
@@ -0,0 +1,97 @@
+package rbac
+
+import (
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Wildcard grants every permission, or every permission below a prefix when it
+// is the last segment, e.g. "withdrawal.*"
+const Wildcard = "*"
+
+// Store resolves the permissions granted to a role
+type Store interface {
+	RolePermissions(role string) ([]string, error)
+}
+
+// Authorizer checks permissions against the roles of a principal
+type Authorizer struct {
+	store Store
+}
+
+var (
+	defaultMu         sync.RWMutex
+	defaultAuthorizer = NewAuthorizer(NewMemoryStore(nil))
+)
+
+// NewAuthorizer creates an authorizer resolving role permissions from a store
+func NewAuthorizer(store Store) *Authorizer {
+	return &Authorizer{store: store}
+}
+
+// Default returns the package-level authorizer. Until SetDefault is called it
+// knows no roles, so only permissions carried directly by a token are granted.
+func Default() *Authorizer {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultAuthorizer
+}
+
+// SetDefault replaces the package-level authorizer
+func SetDefault(a *Authorizer) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultAuthorizer = a
+}
+
+// Permissions returns the permissions granted to any of the roles, deduplicated
+func (a *Authorizer) Permissions(roles []string) ([]string, error) {
+	var permissions []string
+	for _, role := range roles {
+		granted, err := a.store.RolePermissions(role)
+		if err != nil {
+			return nil, err
+		}
+		for _, permission := range granted {
+			if !slices.Contains(permissions, permission) {
+				permissions = append(permissions, permission)
+			}
+		}
+	}
+	return permissions, nil
+}
+
+// Allowed reports whether the roles, together with any directly granted
+// permissions, satisfy every required permission
+func (a *Authorizer) Allowed(roles, granted []string, required ...string) (bool, error) {
+	if satisfiesAll(granted, required) {
+		return true, nil
+	}
+
+	permissions, err := a.Permissions(roles)
+	if err != nil {
+		return false, err
+	}
+	return satisfiesAll(append(permissions, granted...), required), nil
+}
+
+// Match reports whether a granted permission, which may contain a wildcard,
+// covers the required one. Permissions are dot separated, e.g. "withdrawal.approve".
+func Match(granted, required string) bool {
+	if granted == Wildcard || granted == required {
+		return true
+	}
+	prefix, ok := strings.CutSuffix(granted, "."+Wildcard)
+	return ok && strings.HasPrefix(required, prefix+".")
+}
+
+// satisfiesAll reports whether every required permission is matched by a granted one
+func satisfiesAll(granted, required []string) bool {
+	for _, r := range required {
+		if !slices.ContainsFunc(granted, func(g string) bool { return Match(g, r) }) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,121 @@
+package rbac
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+
+	"github.com/writdev-alt/portal-api-shared/redis"
+)
+
+type countingStore struct {
+	Store
+	calls int
+}
+
+func (s *countingStore) RolePermissions(role string) ([]string, error) {
+	s.calls++
+	return s.Store.RolePermissions(role)
+}
+
+type failingStore struct{}
+
+func (failingStore) RolePermissions(string) ([]string, error) {
+	return nil, errors.New("database unavailable")
+}
+
+func TestMatch(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		granted  string
+		required string
+		expected bool
+	}{
+		{"withdrawal.approve", "withdrawal.approve", true},
+		{"withdrawal.approve", "withdrawal.reject", false},
+		{"*", "withdrawal.approve", true},
+		{"withdrawal.*", "withdrawal.approve", true},
+		{"withdrawal.*", "withdrawal.batch.approve", true},
+		{"withdrawal.*", "withdrawal", false},
+		{"withdrawal.*", "withdrawals.approve", false},
+		{"deposit.*", "withdrawal.approve", false},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.granted, tt.required); got != tt.expected {
+			t.Errorf("Match(%q, %q) = %v, expected %v", tt.granted, tt.required, got, tt.expected)
+		}
+	}
+}
+
+func TestAuthorizer_Allowed(t *testing.T) {
+	t.Parallel()
+
+	a := NewAuthorizer(NewMemoryStore(map[string][]string{
+		"finance": {"withdrawal.*", "deposit.read"},
+		"support": {"deposit.read"},
+	}))
+
+	tests := []struct {
+		name     string
+		roles    []string
+		granted  []string
+		required []string
+		expected bool
+	}{
+		{"role wildcard", []string{"finance"}, nil, []string{"withdrawal.approve"}, true},
+		{"all required", []string{"support"}, nil, []string{"deposit.read", "withdrawal.approve"}, false},
+		{"combined roles", []string{"support", "finance"}, nil, []string{"deposit.read", "withdrawal.approve"}, true},
+		{"direct permission", nil, []string{"withdrawal.approve"}, []string{"withdrawal.approve"}, true},
+		{"unknown role", []string{"guest"}, nil, []string{"deposit.read"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed, err := a.Allowed(tt.roles, tt.granted, tt.required...)
+			if err != nil {
+				t.Fatalf("Allowed returned error: %v", err)
+			}
+			if allowed != tt.expected {
+				t.Errorf("Allowed = %v, expected %v", allowed, tt.expected)
+			}
+		})
+	}
+
+	if _, err := NewAuthorizer(failingStore{}).Allowed([]string{"finance"}, nil, "deposit.read"); err == nil {
+		t.Error("expected an error from a failing store")
+	}
+}
+
+func TestCachedStore(t *testing.T) {
+	mr := miniredis.RunT(t)
+	redis.SetClient(goredis.NewClient(&goredis.Options{Addr: mr.Addr()}))
+	t.Cleanup(func() { redis.SetClient(nil) })
+
+	memory := NewMemoryStore(map[string][]string{"finance": {"withdrawal.approve"}})
+	counting := &countingStore{Store: memory}
+	store := NewCachedStore(counting, 0)
+
+	for range 3 {
+		permissions, err := store.RolePermissions("finance")
+		if err != nil {
+			t.Fatalf("RolePermissions returned error: %v", err)
+		}
+		if len(permissions) != 1 || permissions[0] != "withdrawal.approve" {
+			t.Errorf("RolePermissions = %v", permissions)
+		}
+	}
+	if counting.calls != 1 {
+		t.Errorf("store calls = %d, expected 1", counting.calls)
+	}
+
+	memory.SetRolePermissions("finance", "withdrawal.approve", "withdrawal.reject")
+	if err := store.Invalidate("finance"); err != nil {
+		t.Fatalf("Invalidate returned error: %v", err)
+	}
+	permissions, _ := store.RolePermissions("finance")
+	if len(permissions) != 2 {
+		t.Errorf("RolePermissions after Invalidate = %v", permissions)
+	}
+}
@@ -90,6 +90,22 @@ func ReleaseLock(key string) error {
 	return rdb.Del(ctx, key).Err()
 }
 
+// releaseOwnedLockScript deletes a lock only while it still holds the owner's value
+var releaseOwnedLockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// ReleaseOwnedLock deletes the lock only if it still holds value, so a request
+// whose lock expired cannot release the lock another request has taken since.
+// It reports whether the lock was released.
+func ReleaseOwnedLock(key string, value string) (bool, error) {
+	n, err := releaseOwnedLockScript.Run(ctx, rdb, []string{key}, value).Int()
+	return n == 1, err
+}
+
 // Pipeline
 
 func Pipeline(f func(pipe redis.Pipeliner)) error {
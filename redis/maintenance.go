@@ -0,0 +1,21 @@
+package redis
+
+import "time"
+
+const maintenancePrefix = "maintenance:"
+
+// Maintenance windows
+
+func SetMaintenance(scope string, window []byte, expiration time.Duration) error {
+	return rdb.Set(ctx, maintenancePrefix+scope, window, expiration).Err()
+}
+func GetMaintenance(scopes ...string) ([]string, error) {
+	keys := make([]string, len(scopes))
+	for i, scope := range scopes {
+		keys[i] = maintenancePrefix + scope
+	}
+	return MGet(keys...)
+}
+func DeleteMaintenance(scope string) error {
+	return rdb.Del(ctx, maintenancePrefix+scope).Err()
+}
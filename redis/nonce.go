@@ -0,0 +1,12 @@
+package redis
+
+import "time"
+
+const noncePrefix = "nonce:"
+
+// Nonces
+
+// ClaimNonce records a nonce within a scope and reports whether it was unused
+func ClaimNonce(scope, nonce string, expiration time.Duration) (bool, error) {
+	return rdb.SetNX(ctx, noncePrefix+scope+":"+nonce, 1, expiration).Result()
+}
@@ -0,0 +1,16 @@
+package redis
+
+import "github.com/redis/go-redis/v9"
+
+// Pub/Sub
+
+// Subscribe subscribes to channels and waits for the confirmation, so messages
+// published after it returns are delivered. Close the subscription when done.
+func Subscribe(channels ...string) (*redis.PubSub, error) {
+	pubsub := rdb.Subscribe(ctx, channels...)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		_ = pubsub.Close()
+		return nil, err
+	}
+	return pubsub, nil
+}
@@ -0,0 +1,79 @@
+package redis
+
+import (
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const rateLimitPrefix = "ratelimit:"
+
+// RateLimitResult is the outcome of a rate limit check
+type RateLimitResult struct {
+	Allowed   bool
+	Remaining int
+	// RetryAfter is how long to wait before the next request is allowed, zero when allowed
+	RetryAfter time.Duration
+	// ResetAfter is how long until the limit is fully replenished
+	ResetAfter time.Duration
+}
+
+// allowRateScript implements GCRA (generic cell rate algorithm). Only the
+// theoretical arrival time is stored, so each key costs a single string.
+//
+// ARGV: burst, rate, period (ms), now (ms, 0 for the Redis server clock)
+var allowRateScript = redis.NewScript(`
+local burst = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local period = tonumber(ARGV[3])
+local now = tonumber(ARGV[4])
+if now == 0 then
+	local time = redis.call('TIME')
+	now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+end
+
+local emission_interval = period / rate
+local burst_offset = emission_interval * burst
+
+local tat = tonumber(redis.call('GET', KEYS[1]))
+if not tat or tat < now then
+	tat = now
+end
+
+local new_tat = tat + emission_interval
+local diff = now - (new_tat - burst_offset)
+local remaining = math.floor(diff / emission_interval)
+
+if remaining < 0 then
+	return {0, 0, math.ceil(-diff), math.ceil(tat - now)}
+end
+
+local reset_after = new_tat - now
+redis.call('SET', KEYS[1], tostring(new_tat), 'PX', math.ceil(reset_after))
+return {1, remaining, 0, math.ceil(reset_after)}
+`)
+
+// Rate limiting
+
+// AllowRate allows up to rate requests per period with bursts of up to burst requests.
+// Time is read from the Redis server so every instance shares one clock; a non-zero
+// now overrides it and is meant for tests.
+func AllowRate(key string, rate, burst int, period time.Duration, now time.Time) (RateLimitResult, error) {
+	var nowMillis int64
+	if !now.IsZero() {
+		nowMillis = now.UnixMilli()
+	}
+	res, err := allowRateScript.Run(ctx, rdb,
+		[]string{rateLimitPrefix + key},
+		burst, rate, period.Milliseconds(), nowMillis,
+	).Int64Slice()
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	return RateLimitResult{
+		Allowed:    res[0] == 1,
+		Remaining:  int(res[1]),
+		RetryAfter: time.Duration(res[2]) * time.Millisecond,
+		ResetAfter: time.Duration(res[3]) * time.Millisecond,
+	}, nil
+}
@@ -53,6 +53,16 @@ func IsAlive() bool {
 	return rdb.Ping(ctx).Err() == nil
 }
 
+// IsEnabled reports whether a client has been configured, without pinging the server
+func IsEnabled() bool {
+	return rdb != nil
+}
+
+// SetClient replaces the client used by the package helpers
+func SetClient(client *redis.Client) {
+	rdb = client
+}
+
 func GetRedis() *redis.Client {
 	if rdb == nil {
 		panic("Redis client is not initialized. Call Setup() first.")
@@ -0,0 +1,178 @@
+package redis
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const tokenFamilyPrefix = "auth:token_family:"
+
+// RotateResult is the outcome of rotating a refresh token family
+type RotateResult int
+
+const (
+	// TokenFamilyNotFound means the family expired or was revoked
+	TokenFamilyNotFound RotateResult = iota
+	// TokenRotated means the presented token was current and has been replaced
+	TokenRotated
+	// TokenReuseDetected means an already rotated token was presented; the family is now revoked
+	TokenReuseDetected
+)
+
+// rotateTokenFamilyScript compares the presented token ID with the current one
+// and swaps it atomically. A mismatch means the token was already used, so the
+// whole family is deleted.
+var rotateTokenFamilyScript = redis.NewScript(`
+local current = redis.call('HGET', KEYS[1], 'current')
+if not current then
+	return 0
+end
+if current ~= ARGV[1] then
+	redis.call('DEL', KEYS[1])
+	return 2
+end
+redis.call('HSET', KEYS[1], 'current', ARGV[2])
+redis.call('PEXPIRE', KEYS[1], ARGV[3])
+return 1
+`)
+
+// TokenStore keeps refresh token families, revocations and action nonces in
+// one Redis client. The package level token helpers use the shared client.
+type TokenStore struct {
+	client *redis.Client
+}
+
+// NewTokenStore creates a token store backed by client
+func NewTokenStore(client *redis.Client) *TokenStore {
+	return &TokenStore{client: client}
+}
+
+// Token families
+
+func (s *TokenStore) CreateTokenFamily(familyID, tokenID, subject string, expiration time.Duration) error {
+	key := tokenFamilyPrefix + familyID
+	_, err := s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, "current", tokenID, "subject", subject)
+		pipe.PExpire(ctx, key, expiration)
+		return nil
+	})
+	return err
+}
+func (s *TokenStore) RotateTokenFamily(familyID, presentedID, nextID string, expiration time.Duration) (RotateResult, error) {
+	res, err := rotateTokenFamilyScript.Run(ctx, s.client,
+		[]string{tokenFamilyPrefix + familyID},
+		presentedID, nextID, expiration.Milliseconds(),
+	).Int()
+	if err != nil {
+		return TokenFamilyNotFound, err
+	}
+	return RotateResult(res), nil
+}
+func (s *TokenStore) RevokeTokenFamily(familyID string) error {
+	return s.client.Del(ctx, tokenFamilyPrefix+familyID).Err()
+}
+
+const (
+	tokenDenylistPrefix  = "auth:token_denylist:"
+	tokenWatermarkPrefix = "auth:token_watermark:"
+)
+
+// Token revocation
+
+func (s *TokenStore) DenyToken(tokenID string, expiration time.Duration) error {
+	return s.client.Set(ctx, tokenDenylistPrefix+tokenID, 1, expiration).Err()
+}
+func (s *TokenStore) SetTokenWatermark(subject string, issuedBefore time.Time, expiration time.Duration) error {
+	return s.client.Set(ctx, tokenWatermarkPrefix+subject, issuedBefore.UnixNano(), expiration).Err()
+}
+func (s *TokenStore) ClearTokenWatermark(subject string) error {
+	return s.client.Del(ctx, tokenWatermarkPrefix+subject).Err()
+}
+
+// IsTokenRevoked reports whether the token ID is denylisted or the token was
+// issued at or before the watermark of any of the given subjects. Watermarks are
+// stored in nanoseconds so a token issued right after a revocation is not caught
+// by it. Everything is fetched in a single round trip.
+func (s *TokenStore) IsTokenRevoked(tokenID string, issuedAt time.Time, subjects ...string) (bool, error) {
+	keys := make([]string, 0, len(subjects)+1)
+	keys = append(keys, tokenDenylistPrefix+tokenID)
+	for _, subject := range subjects {
+		keys = append(keys, tokenWatermarkPrefix+subject)
+	}
+
+	values, err := s.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return false, err
+	}
+
+	if tokenID != "" && values[0] != nil {
+		return true, nil
+	}
+	for _, v := range values[1:] {
+		if v == nil {
+			continue
+		}
+		watermark, err := strconv.ParseInt(v.(string), 10, 64)
+		if err != nil {
+			return false, err
+		}
+		if issuedAt.UnixNano() <= watermark {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+const actionNoncePrefix = "auth:action_nonce:"
+
+// Action tokens
+
+func (s *TokenStore) StoreActionNonce(nonce, purpose string, expiration time.Duration) error {
+	return s.client.Set(ctx, actionNoncePrefix+nonce, purpose, expiration).Err()
+}
+
+// ConsumeActionNonce deletes the nonce and reports whether it was still stored
+// for the given purpose, so each nonce is accepted at most once.
+func (s *TokenStore) ConsumeActionNonce(nonce, purpose string) (bool, error) {
+	val, err := s.client.GetDel(ctx, actionNoncePrefix+nonce).Result()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return val == purpose, nil
+}
+
+// Shared client
+
+func CreateTokenFamily(familyID, tokenID, subject string, expiration time.Duration) error {
+	return NewTokenStore(rdb).CreateTokenFamily(familyID, tokenID, subject, expiration)
+}
+func RotateTokenFamily(familyID, presentedID, nextID string, expiration time.Duration) (RotateResult, error) {
+	return NewTokenStore(rdb).RotateTokenFamily(familyID, presentedID, nextID, expiration)
+}
+func RevokeTokenFamily(familyID string) error {
+	return NewTokenStore(rdb).RevokeTokenFamily(familyID)
+}
+func DenyToken(tokenID string, expiration time.Duration) error {
+	return NewTokenStore(rdb).DenyToken(tokenID, expiration)
+}
+func SetTokenWatermark(subject string, issuedBefore time.Time, expiration time.Duration) error {
+	return NewTokenStore(rdb).SetTokenWatermark(subject, issuedBefore, expiration)
+}
+func ClearTokenWatermark(subject string) error {
+	return NewTokenStore(rdb).ClearTokenWatermark(subject)
+}
+func IsTokenRevoked(tokenID string, issuedAt time.Time, subjects ...string) (bool, error) {
+	return NewTokenStore(rdb).IsTokenRevoked(tokenID, issuedAt, subjects...)
+}
+func StoreActionNonce(nonce, purpose string, expiration time.Duration) error {
+	return NewTokenStore(rdb).StoreActionNonce(nonce, purpose, expiration)
+}
+func ConsumeActionNonce(nonce, purpose string) (bool, error) {
+	return NewTokenStore(rdb).ConsumeActionNonce(nonce, purpose)
+}
@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/writdev-alt/portal-api-shared/apikey"
+)
+
+// APIKeyRepository stores merchant API keys and implements apikey.Store
+type APIKeyRepository struct {
+	BaseRepository[apikey.APIKey]
+}
+
+// NewAPIKeyRepository creates a new API key repository
+func NewAPIKeyRepository(db *gorm.DB) *APIKeyRepository {
+	return &APIKeyRepository{
+		BaseRepository: NewBaseRepository[apikey.APIKey](db),
+	}
+}
+
+// FindByPrefix finds a key by its public prefix
+func (r *APIKeyRepository) FindByPrefix(prefix string) (*apikey.APIKey, error) {
+	return r.FindOne(map[string]interface{}{"prefix": prefix})
+}
+
+// UpdateLastUsed records when a key was last used without touching updated_at
+func (r *APIKeyRepository) UpdateLastUsed(id uint, at time.Time) error {
+	if err := r.GetDB().Model(&apikey.APIKey{}).Where("id = ?", id).UpdateColumn("last_used_at", at).Error; err != nil {
+		return fmt.Errorf("failed to update last used: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"gorm.io/gorm"
+
+	"github.com/writdev-alt/portal-api-shared/ipwhitelist"
+	"github.com/writdev-alt/portal-api-shared/logger"
+)
+
+// IPWhitelistRepository stores merchant IP whitelist entries and implements
+// ipwhitelist.Source. Every successful write publishes the changed owners with
+// ipwhitelist.NotifyChanged, so cached lists are reloaded on all instances.
+type IPWhitelistRepository struct {
+	BaseRepository[ipwhitelist.Entry]
+}
+
+// NewIPWhitelistRepository creates a new IP whitelist repository
+func NewIPWhitelistRepository(db *gorm.DB) *IPWhitelistRepository {
+	return &IPWhitelistRepository{
+		BaseRepository: NewBaseRepository[ipwhitelist.Entry](db),
+	}
+}
+
+// Create validates and normalizes the address before storing an entry
+func (r *IPWhitelistRepository) Create(entry *ipwhitelist.Entry) error {
+	if err := normalizeIPWhitelistAddress(entry); err != nil {
+		return err
+	}
+	if err := r.BaseRepository.Create(entry); err != nil {
+		return err
+	}
+	notifyIPWhitelistChanged(entry)
+	return nil
+}
+
+// Update validates and normalizes the address before saving an entry
+func (r *IPWhitelistRepository) Update(entry *ipwhitelist.Entry) error {
+	if err := normalizeIPWhitelistAddress(entry); err != nil {
+		return err
+	}
+	// The entry may move to another owner, whose list changes as well
+	previous, err := r.FindByID(entry.ID)
+	if err != nil {
+		return err
+	}
+	if err := r.BaseRepository.Update(entry); err != nil {
+		return err
+	}
+	notifyIPWhitelistChanged(previous, entry)
+	return nil
+}
+
+// UpdateByID validates and normalizes the address when it is updated
+func (r *IPWhitelistRepository) UpdateByID(id interface{}, updates map[string]interface{}) error {
+	if value, ok := updates["address"]; ok {
+		address, _ := value.(string)
+		prefix, err := ipwhitelist.ParseEntry(address)
+		if err != nil {
+			return err
+		}
+		updates["address"] = prefix.String()
+	}
+	previous, err := r.FindByID(id)
+	if err != nil {
+		return err
+	}
+	if err := r.BaseRepository.UpdateByID(id, updates); err != nil {
+		return err
+	}
+	if previous == nil {
+		return nil
+	}
+	current := *previous
+	if merchantID, ok := updates["merchant_id"].(string); ok {
+		current.MerchantID = merchantID
+	}
+	if apiKeyID, ok := updates["api_key_id"].(string); ok {
+		current.APIKeyID = apiKeyID
+	}
+	notifyIPWhitelistChanged(previous, &current)
+	return nil
+}
+
+// Delete soft deletes an entry
+func (r *IPWhitelistRepository) Delete(id interface{}) error {
+	previous, err := r.FindByID(id)
+	if err != nil {
+		return err
+	}
+	if err := r.BaseRepository.Delete(id); err != nil {
+		return err
+	}
+	notifyIPWhitelistChanged(previous)
+	return nil
+}
+
+// HardDelete permanently deletes an entry
+func (r *IPWhitelistRepository) HardDelete(id interface{}) error {
+	// A soft deleted entry is no longer listed, so it needs no notification
+	previous, err := r.FindByID(id)
+	if err != nil {
+		return err
+	}
+	if err := r.BaseRepository.HardDelete(id); err != nil {
+		return err
+	}
+	notifyIPWhitelistChanged(previous)
+	return nil
+}
+
+// Entries returns the addresses of a merchant's list, or of one of its API keys
+func (r *IPWhitelistRepository) Entries(owner ipwhitelist.Owner) ([]string, error) {
+	var addresses []string
+	err := r.GetDB().Model(&ipwhitelist.Entry{}).
+		Where("merchant_id = ? AND api_key_id = ?", owner.MerchantID, owner.APIKeyID).
+		Pluck("address", &addresses).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load IP whitelist: %w", err)
+	}
+	return addresses, nil
+}
+
+// normalizeIPWhitelistAddress rejects an invalid IP or CIDR and stores it masked
+func normalizeIPWhitelistAddress(entry *ipwhitelist.Entry) error {
+	prefix, err := ipwhitelist.ParseEntry(entry.Address)
+	if err != nil {
+		return err
+	}
+	entry.Address = prefix.String()
+	return nil
+}
+
+// notifyIPWhitelistChanged publishes the owners of entries. The write has already
+// succeeded, so a failed publish is only logged; caches expire after their TTL.
+func notifyIPWhitelistChanged(entries ...*ipwhitelist.Entry) {
+	var owners []ipwhitelist.Owner
+	for _, entry := range entries {
+		if entry == nil {
+			continue
+		}
+		owner := ipwhitelist.Owner{MerchantID: entry.MerchantID, APIKeyID: entry.APIKeyID}
+		if !slices.Contains(owners, owner) {
+			owners = append(owners, owner)
+		}
+	}
+	if len(owners) == 0 {
+		return
+	}
+	if err := ipwhitelist.NotifyChanged(owners...); err != nil && !errors.Is(err, ipwhitelist.ErrRedisDisabled) {
+		logger.Warnf("Failed to publish IP whitelist change of %v: %v", owners, err)
+	}
+}
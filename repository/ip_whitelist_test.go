@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/writdev-alt/portal-api-shared/ipwhitelist"
+	"github.com/writdev-alt/portal-api-shared/redis"
+)
+
+// fakeConn answers every write as successful and every select with stored
+type fakeConn struct {
+	stored *ipwhitelist.Entry
+}
+
+func (c *fakeConn) Connect(context.Context) (driver.Conn, error) { return c, nil }
+func (c *fakeConn) Driver() driver.Driver                        { return nil }
+func (c *fakeConn) Prepare(string) (driver.Stmt, error)          { return nil, errors.New("not supported") }
+func (c *fakeConn) Close() error                                 { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)                    { return nil, errors.New("not supported") }
+
+func (c *fakeConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	return fakeResult{}, nil
+}
+
+type fakeResult struct{}
+
+func (fakeResult) LastInsertId() (int64, error) { return 1, nil }
+func (fakeResult) RowsAffected() (int64, error) { return 1, nil }
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	if !strings.HasPrefix(query, "SELECT") {
+		return nil, errors.New("unexpected query: " + query)
+	}
+	return &fakeRows{entry: c.stored}, nil
+}
+
+type fakeRows struct {
+	entry *ipwhitelist.Entry
+}
+
+func (r *fakeRows) Columns() []string {
+	return []string{"id", "merchant_id", "api_key_id", "address"}
+}
+
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.entry == nil {
+		return io.EOF
+	}
+	dest[0], dest[1], dest[2], dest[3] = int64(r.entry.ID), r.entry.MerchantID, r.entry.APIKeyID, r.entry.Address
+	r.entry = nil
+	return nil
+}
+
+func newTestIPWhitelistRepository(t *testing.T, stored *ipwhitelist.Entry) *IPWhitelistRepository {
+	t.Helper()
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      sql.OpenDB(&fakeConn{stored: stored}),
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{SkipDefaultTransaction: true, Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("gorm.Open returned error: %v", err)
+	}
+	return NewIPWhitelistRepository(db)
+}
+
+// subscribeIPWhitelistChanges returns a function receiving the next published owner
+func subscribeIPWhitelistChanges(t *testing.T) func() (ipwhitelist.Owner, bool) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	redis.SetClient(goredis.NewClient(&goredis.Options{Addr: mr.Addr()}))
+	t.Cleanup(func() { redis.SetClient(nil) })
+
+	sub, err := redis.Subscribe(ipwhitelist.InvalidationChannel)
+	if err != nil {
+		t.Fatalf("Subscribe returned error: %v", err)
+	}
+	t.Cleanup(func() { sub.Close() })
+
+	return func() (ipwhitelist.Owner, bool) {
+		select {
+		case msg := <-sub.Channel():
+			var owner ipwhitelist.Owner
+			if err := json.Unmarshal([]byte(msg.Payload), &owner); err != nil {
+				t.Fatalf("invalid invalidation %q: %v", msg.Payload, err)
+			}
+			return owner, true
+		case <-time.After(200 * time.Millisecond):
+			return ipwhitelist.Owner{}, false
+		}
+	}
+}
+
+func TestIPWhitelistRepository_NotifiesChanges(t *testing.T) {
+	next := subscribeIPWhitelistChanges(t)
+	merchant := ipwhitelist.Owner{MerchantID: "merchant-1"}
+	apiKey := ipwhitelist.Owner{MerchantID: "merchant-1", APIKeyID: "key-1"}
+	stored := func() *ipwhitelist.Entry {
+		return &ipwhitelist.Entry{ID: 1, MerchantID: "merchant-1", Address: "203.0.113.1/32"}
+	}
+
+	expect := func(name string, owners ...ipwhitelist.Owner) {
+		t.Helper()
+		for _, want := range owners {
+			if got, ok := next(); !ok || got != want {
+				t.Errorf("%s: published %+v (%v), expected %+v", name, got, ok, want)
+			}
+		}
+		if got, ok := next(); ok {
+			t.Errorf("%s: unexpected publish of %+v", name, got)
+		}
+	}
+
+	repo := newTestIPWhitelistRepository(t, nil)
+	if err := repo.Create(&ipwhitelist.Entry{MerchantID: "merchant-1", Address: "203.0.113.1"}); err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	expect("create", merchant)
+
+	if err := repo.Create(&ipwhitelist.Entry{MerchantID: "merchant-1", Address: "not-an-ip"}); err == nil {
+		t.Error("Create accepted an invalid address")
+	}
+	expect("invalid create")
+
+	repo = newTestIPWhitelistRepository(t, stored())
+	entry := stored()
+	entry.APIKeyID = "key-1"
+	if err := repo.Update(entry); err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+	expect("update to another owner", merchant, apiKey)
+
+	repo = newTestIPWhitelistRepository(t, stored())
+	if err := repo.UpdateByID(1, map[string]interface{}{"address": "203.0.113.2"}); err != nil {
+		t.Fatalf("UpdateByID returned error: %v", err)
+	}
+	expect("update by ID", merchant)
+
+	repo = newTestIPWhitelistRepository(t, stored())
+	if err := repo.Delete(1); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	expect("delete", merchant)
+
+	repo = newTestIPWhitelistRepository(t, stored())
+	if err := repo.HardDelete(1); err != nil {
+		t.Fatalf("HardDelete returned error: %v", err)
+	}
+	expect("hard delete", merchant)
+}
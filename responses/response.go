@@ -6,28 +6,45 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// ContextKeyResponseCode holds the code of the response written by this package,
+// so access logs can record it
+const ContextKeyResponseCode = "response_code"
+
+// ContextKeyRequestID holds the request ID echoed in CommonResponse bodies. It is
+// set by middleware.RequestID when IncludeInBody is enabled.
+const ContextKeyRequestID = "response_request_id"
+
 type CommonResponse struct {
-	Code    int         `json:"code"` // Custom response code: HTTP_STATUS + SERVICE_CODE + CASE_CODE (e.g., 2000401)
-	Message string      `json:"message"`
-	Data    interface{} `json:"data"`
+	Code      int         `json:"code"` // Custom response code: HTTP_STATUS + SERVICE_CODE + CASE_CODE (e.g., 2000401)
+	Message   string      `json:"message"`
+	Data      interface{} `json:"data"`
+	RequestID string      `json:"requestId,omitempty"` // Set when request IDs are echoed in bodies, see ContextKeyRequestID
 }
 
 // Result creates a response with custom code system
 func Result(ctx *gin.Context, httpStatus int, serviceCode, caseCode string, data interface{}, message string) {
 	responseCode := BuildResponseCode(httpStatus, serviceCode, caseCode)
-	ctx.JSON(httpStatus, CommonResponse{
-		Code:    responseCode,
-		Message: message,
-		Data:    data,
+	render(ctx, httpStatus, responseCode, CommonResponse{
+		Code:      responseCode,
+		Message:   message,
+		Data:      data,
+		RequestID: ctx.GetString(ContextKeyRequestID),
 	})
 }
 
+// render writes a JSON response and records its code in the context
+func render(ctx *gin.Context, httpStatus int, responseCode interface{}, body interface{}) {
+	ctx.Set(ContextKeyResponseCode, responseCode)
+	ctx.JSON(httpStatus, body)
+}
+
 // ResultWithCode creates a response with explicit response code
 func ResultWithCode(ctx *gin.Context, httpStatus int, responseCode int, data interface{}, message string) {
-	ctx.JSON(httpStatus, CommonResponse{
-		Code:    responseCode,
-		Message: message,
-		Data:    data,
+	render(ctx, httpStatus, responseCode, CommonResponse{
+		Code:      responseCode,
+		Message:   message,
+		Data:      data,
+		RequestID: ctx.GetString(ContextKeyRequestID),
 	})
 }
 
@@ -65,7 +82,7 @@ type CursorPaginationInput struct {
 // CursorPaginated returns a cursor-based paginated response with fields at the top level
 func CursorPaginated(ctx *gin.Context, httpStatus int, serviceCode, caseCode string, pagination CursorPaginationInput, message string) {
 	responseCode := BuildResponseCode(httpStatus, serviceCode, caseCode)
-	ctx.JSON(httpStatus, CursorPaginatedResponse{
+	render(ctx, httpStatus, responseCode, CursorPaginatedResponse{
 		Code:       responseCode,
 		Message:    message,
 		Data:       pagination.Data,
@@ -97,7 +114,7 @@ type SimplePaginationInput struct {
 // SimplePaginated returns a simple paginated response with fields at the top level
 func SimplePaginated(ctx *gin.Context, httpStatus int, serviceCode, caseCode string, pagination SimplePaginationInput, message string) {
 	responseCode := BuildResponseCode(httpStatus, serviceCode, caseCode)
-	ctx.JSON(httpStatus, SimplePaginatedResponse{
+	render(ctx, httpStatus, responseCode, SimplePaginatedResponse{
 		Code:       responseCode,
 		Message:    message,
 		Data:       pagination.Data,
@@ -159,7 +176,7 @@ func ValidationError(ctx *gin.Context, serviceCode string, err error) {
 
 	responseCode := BuildResponseCode(http.StatusUnprocessableEntity, serviceCode, CaseCodeValidationError)
 
-	ctx.JSON(http.StatusUnprocessableEntity, ValidationErrorResponse{
+	render(ctx, http.StatusUnprocessableEntity, responseCode, ValidationErrorResponse{
 		Code:    responseCode,
 		Message: message,
 		Errors:  errors,
@@ -177,7 +194,7 @@ func ValidationErrorWithMessage(ctx *gin.Context, serviceCode string, message st
 
 	responseCode := BuildResponseCode(http.StatusUnprocessableEntity, serviceCode, CaseCodeValidationError)
 
-	ctx.JSON(http.StatusUnprocessableEntity, ValidationErrorResponse{
+	render(ctx, http.StatusUnprocessableEntity, responseCode, ValidationErrorResponse{
 		Code:    responseCode,
 		Message: message,
 		Errors:  errors,
@@ -0,0 +1,135 @@
+package response
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SNAP BI service codes for the APIs served by our services
+const (
+	SnapServiceCodeAccessTokenB2B        = "73" // Access token B2B
+	SnapServiceCodeTransferIntrabank     = "17" // Intrabank transfer
+	SnapServiceCodeTransferInterbank     = "18" // Interbank transfer
+	SnapServiceCodeTransferStatus        = "36" // Transfer status inquiry
+	SnapServiceCodeBalanceInquiry        = "11" // Balance inquiry
+	SnapServiceCodeVirtualAccountInquiry = "24" // Virtual account inquiry
+	SnapServiceCodeVirtualAccountPayment = "25" // Virtual account payment
+	SnapServiceCodeQRISGenerate          = "47" // QRIS MPM generate
+	SnapServiceCodeQRISNotify            = "52" // QRIS MPM notify
+	SnapServiceCodeTransactionHistory    = "12" // Transaction history list
+)
+
+// snapCaseCodes maps our case codes to the SNAP BI case code for an HTTP status.
+// Case codes without an entry map to "00", the generic case of every status.
+var snapCaseCodes = map[int]map[string]string{
+	http.StatusBadRequest: {
+		CaseCodeInvalidFormat: "01", // Invalid Field Format
+		CaseCodeInvalidEmail:  "01",
+		CaseCodeInvalidDate:   "01",
+		CaseCodeRequiredField: "02", // Invalid Mandatory Field
+	},
+	http.StatusUnauthorized: {
+		CaseCodeInvalidToken:   "01", // Invalid Token (B2B)
+		CaseCodeTokenExpired:   "01",
+		CaseCodeSessionExpired: "02", // Invalid Customer Token
+	},
+	http.StatusForbidden: {
+		CaseCodeExpiredTransaction:  "00", // Transaction Expired
+		CaseCodeOperationNotAllowed: "01", // Feature Not Allowed
+		CaseCodePermissionDenied:    "01",
+		CaseCodeLimitExceeded:       "02", // Exceeds Transaction Amount Limit
+		CaseCodeAccountLocked:       "05", // Do Not Honor
+		CaseCodeInsufficientBalance: "14", // Insufficient Funds
+		CaseCodeAccountDisabled:     "18", // Inactive Card/Account/Customer
+	},
+	http.StatusNotFound: {
+		CaseCodeInvalidStatus:       "00", // Invalid Transaction Status
+		CaseCodeTransactionNotFound: "01", // Transaction Not Found
+		CaseCodeUserNotFound:        "11", // Invalid Card/Account/Customer
+		CaseCodeMerchantNotFound:    "08", // Invalid Merchant
+		CaseCodeInvalidAmount:       "13", // Invalid Amount
+	},
+	http.StatusConflict: {
+		CaseCodeDuplicateEntry:   "01", // Duplicate partnerReferenceNo
+		CaseCodeAlreadyProcessed: "01",
+	},
+	http.StatusInternalServerError: {
+		CaseCodeInternalError:        "01", // Internal Server Error
+		CaseCodeDatabaseError:        "01",
+		CaseCodeExternalServiceError: "02", // External Server Error
+	},
+}
+
+// snapMessages holds the standard SNAP BI response message per HTTP status and case code
+var snapMessages = map[int]map[string]string{
+	http.StatusOK:                  {"00": "Successful"},
+	http.StatusAccepted:            {"00": "Request In Progress"},
+	http.StatusBadRequest:          {"00": "Bad Request", "01": "Invalid Field Format", "02": "Invalid Mandatory Field"},
+	http.StatusUnauthorized:        {"00": "Unauthorized", "01": "Invalid Token (B2B)", "02": "Invalid Customer Token"},
+	http.StatusForbidden:           {"00": "Transaction Expired", "01": "Feature Not Allowed", "02": "Exceeds Transaction Amount Limit", "05": "Do Not Honor", "14": "Insufficient Funds", "18": "Inactive Card/Account/Customer"},
+	http.StatusNotFound:            {"00": "Invalid Transaction Status", "01": "Transaction Not Found", "08": "Invalid Merchant", "11": "Invalid Card/Account/Customer", "13": "Invalid Amount"},
+	http.StatusConflict:            {"00": "Conflict", "01": "Duplicate partnerReferenceNo"},
+	http.StatusTooManyRequests:     {"00": "Too Many Requests"},
+	http.StatusInternalServerError: {"00": "General Error", "01": "Internal Server Error", "02": "External Server Error"},
+	http.StatusGatewayTimeout:      {"00": "Timeout"},
+}
+
+// SnapResponse is the envelope of every SNAP BI response
+type SnapResponse struct {
+	ResponseCode    string `json:"responseCode"`
+	ResponseMessage string `json:"responseMessage"`
+}
+
+// SnapCaseCode maps one of our case codes to the SNAP BI case code for an HTTP status
+func SnapCaseCode(httpStatus int, caseCode string) string {
+	if snap, ok := snapCaseCodes[httpStatus][caseCode]; ok {
+		return snap
+	}
+	return "00"
+}
+
+// SnapResponseCodeFromCode converts a code built by BuildResponseCode into a SNAP BI
+// response code (HTTP status + SNAP service code + SNAP case code, 7 characters)
+func SnapResponseCodeFromCode(code int, snapServiceCode string) string {
+	httpStatus, _, caseCode := ParseResponseCode(code)
+	return BuildSnapResponseCode(httpStatus, snapServiceCode, caseCode)
+}
+
+// BuildSnapResponseCode builds a SNAP BI response code from an HTTP status, a SNAP
+// service code and one of our case codes, e.g. 200 + "73" + CaseCodeSuccess = "2007300"
+func BuildSnapResponseCode(httpStatus int, snapServiceCode, caseCode string) string {
+	return fmt.Sprintf("%03d%s%s", httpStatus, snapServiceCode, SnapCaseCode(httpStatus, caseCode))
+}
+
+// SnapMessage returns the standard SNAP BI message for an HTTP status and one of our case codes
+func SnapMessage(httpStatus int, caseCode string) string {
+	if message, ok := snapMessages[httpStatus][SnapCaseCode(httpStatus, caseCode)]; ok {
+		return message
+	}
+	return http.StatusText(httpStatus)
+}
+
+// SnapResult writes a SNAP BI response. fields are placed next to responseCode and
+// responseMessage at the top level, as SNAP requires. An empty message uses the
+// standard SNAP message for the status and case code.
+func SnapResult(ctx *gin.Context, httpStatus int, snapServiceCode, caseCode string, fields interface{}, message string) {
+	if message == "" {
+		message = SnapMessage(httpStatus, caseCode)
+	}
+
+	body := map[string]interface{}{}
+	if fields != nil {
+		// Flatten the fields into the envelope
+		if data, err := json.Marshal(fields); err == nil {
+			_ = json.Unmarshal(data, &body)
+		}
+	}
+	responseCode := BuildSnapResponseCode(httpStatus, snapServiceCode, caseCode)
+	body["responseCode"] = responseCode
+	body["responseMessage"] = message
+
+	render(ctx, httpStatus, responseCode, body)
+}
@@ -0,0 +1,37 @@
+package response
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestBuildSnapResponseCode(t *testing.T) {
+	tests := []struct {
+		httpStatus  int
+		serviceCode string
+		caseCode    string
+		expected    string
+	}{
+		{http.StatusOK, SnapServiceCodeAccessTokenB2B, CaseCodeSuccess, "2007300"},
+		{http.StatusBadRequest, SnapServiceCodeTransferIntrabank, CaseCodeRequiredField, "4001702"},
+		{http.StatusUnauthorized, SnapServiceCodeTransferIntrabank, CaseCodeInvalidToken, "4011701"},
+		{http.StatusForbidden, SnapServiceCodeTransferIntrabank, CaseCodeInsufficientBalance, "4031714"},
+		{http.StatusConflict, SnapServiceCodeTransferIntrabank, CaseCodeConflict, "4091700"},
+		{http.StatusInternalServerError, SnapServiceCodeTransferIntrabank, CaseCodeExternalServiceError, "5001702"},
+	}
+	for _, tt := range tests {
+		if got := BuildSnapResponseCode(tt.httpStatus, tt.serviceCode, tt.caseCode); got != tt.expected {
+			t.Errorf("BuildSnapResponseCode(%d, %s, %s) = %s, expected %s", tt.httpStatus, tt.serviceCode, tt.caseCode, got, tt.expected)
+		}
+	}
+}
+
+func TestSnapResponseCodeFromCode(t *testing.T) {
+	code := BuildResponseCode(http.StatusNotFound, ServiceCodeTransaction, CaseCodeTransactionNotFound)
+	if got := SnapResponseCodeFromCode(code, SnapServiceCodeTransferStatus); got != "4043601" {
+		t.Errorf("SnapResponseCodeFromCode = %s, expected 4043601", got)
+	}
+	if got := SnapMessage(http.StatusNotFound, CaseCodeTransactionNotFound); got != "Transaction Not Found" {
+		t.Errorf("SnapMessage = %s, expected Transaction Not Found", got)
+	}
+}